@@ -0,0 +1,30 @@
+package achievements
+
+import "time"
+
+// Badge is an achievement a user has unlocked.
+type Badge struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+}
+
+// Achievement is a rule as seen by one user, with how far along they are.
+type Achievement struct {
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Progress    int        `json:"progress"`
+	Target      int        `json:"target"`
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+}
+
+func newBadge(rule Rule, unlockedAt time.Time) Badge {
+	return Badge{
+		Id:          rule.Id,
+		Name:        rule.Name,
+		Description: rule.Description,
+		UnlockedAt:  unlockedAt,
+	}
+}
@@ -0,0 +1,27 @@
+package achievements
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	pool *pgxpool.Pool
+
+	ErrorRuleIdRequired    = errors.New("achievement id is required")
+	ErrorRuleDuplicate     = errors.New("achievement id is used more than once")
+	ErrorRuleUnknownEvent  = errors.New("achievement event must be one of catch, release, unrelease, rename, trade, level_up or evolve")
+	ErrorRuleUnknownCount  = errors.New("achievement count must be events, pokemon or species")
+	ErrorRuleUnknownPer    = errors.New("achievement per must be empty, pokemon, type or generation")
+	ErrorRuleInvalidTarget = errors.New("achievement target must be positive unless complete is set")
+)
+
+func SetPool(newPool *pgxpool.Pool) error {
+	if newPool == nil {
+		return errors.New("Cannot assign nil pool")
+	}
+
+	pool = newPool
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package achievements
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"strings"
+	"time"
+)
+
+// progressQuery turns a rule into SQL over the user's Pokémon event log.
+// Only parameters come from the rule's free-form fields; Count and Per are
+// validated against fixed lists before they reach here.
+func progressQuery(rule Rule, userId ulid.ULID) (string, []interface{}) {
+	args := []interface{}{userId, rule.Event}
+	from := `users_pokemons_events e`
+	conds := []string{`e.owner_id = $1`, `e.kind = $2`}
+
+	needsSpecies := rule.Filter.Generation != "" || rule.Filter.Type != "" ||
+		rule.Per == PerType || rule.Per == PerGeneration
+	if needsSpecies {
+		from += ` JOIN pokemon_species s ON s.id = e.pokemon_id`
+	}
+
+	if rule.Filter.Generation != "" {
+		args = append(args, rule.Filter.Generation)
+		conds = append(conds, fmt.Sprintf(`s.generation = $%d`, len(args)))
+	}
+
+	if rule.Filter.Type != "" {
+		args = append(args, rule.Filter.Type)
+		conds = append(conds, fmt.Sprintf(`$%d = ANY(s.types)`, len(args)))
+	}
+
+	count := `count(*)`
+	switch rule.Count {
+	case CountPokemon:
+		count = `count(DISTINCT e.user_pokemon_id)`
+	case CountSpecies:
+		count = `count(DISTINCT e.pokemon_id)`
+	}
+
+	group := ""
+	switch rule.Per {
+	case PerPokemon:
+		group = `e.user_pokemon_id`
+	case PerType:
+		from += ` CROSS JOIN LATERAL unnest(s.types) AS t(type)`
+		group = `t.type`
+	case PerGeneration:
+		group = `s.generation`
+	}
+
+	query := `SELECT ` + count + ` AS n FROM ` + from + ` WHERE ` + strings.Join(conds, ` AND `)
+	if group != "" {
+		query = `SELECT COALESCE(max(n), 0) FROM (` + query + ` GROUP BY ` + group + `) g`
+	}
+
+	return query, args
+}
+
+func findProgress(ctx context.Context, tx pgx.Tx, rule Rule, userId ulid.ULID) (int, error) {
+	query, args := progressQuery(rule, userId)
+
+	var progress int
+	err := tx.QueryRow(ctx, query, args...).Scan(&progress)
+
+	return progress, err
+}
+
+// findTarget is the rule's target, or for a complete rule the number of
+// catalogued species it covers.
+func findTarget(ctx context.Context, tx pgx.Tx, rule Rule) (int, error) {
+	if !rule.Complete {
+		return rule.Target, nil
+	}
+
+	query := `SELECT count(*) FROM pokemon_species
+			 WHERE ($1 = '' OR generation = $1) AND ($2 = '' OR $2 = ANY(types))`
+
+	var target int
+	err := tx.QueryRow(ctx, query, rule.Filter.Generation, rule.Filter.Type).Scan(&target)
+
+	return target, err
+}
+
+func findUnlocked(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (map[string]time.Time, error) {
+	query := `SELECT achievement_id, unlocked_at
+				FROM users_achievements
+			 WHERE user_id = $1`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unlocked := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+
+		unlocked[id] = at
+	}
+
+	return unlocked, rows.Err()
+}
+
+func saveUnlocked(ctx context.Context, tx pgx.Tx, userId ulid.ULID, achievementId string, unlockedAt time.Time) error {
+	query := `INSERT INTO users_achievements (user_id, achievement_id, unlocked_at)
+				VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING`
+
+	_, err := tx.Exec(ctx, query, userId, achievementId, unlockedAt)
+
+	return err
+}
@@ -0,0 +1,65 @@
+package achievements
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"mda/helper"
+	"net/http"
+)
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Get("/", listAchievementsHandler)
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func listAchievementsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	list, err := listAchievements(ctx, userId)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
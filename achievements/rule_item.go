@@ -0,0 +1,137 @@
+package achievements
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	CountEvents  = "events"
+	CountPokemon = "pokemon"
+	CountSpecies = "species"
+)
+
+const (
+	PerPokemon    = "pokemon"
+	PerType       = "type"
+	PerGeneration = "generation"
+)
+
+// events mirrors the history kinds userspokemon records. The two lists
+// are kept apart so this package stays free of userspokemon.
+var events = map[string]bool{
+	"catch":     true,
+	"release":   true,
+	"unrelease": true,
+	"rename":    true,
+	"trade":     true,
+	"level_up":  true,
+	"evolve":    true,
+}
+
+//go:embed rules.json
+var defaultRules []byte
+
+var rules = mustParseRules(defaultRules)
+
+// Filter narrows the events a rule counts to species of a generation or
+// type, as named by PokeAPI.
+type Filter struct {
+	Generation string `json:"generation,omitempty"`
+	Type       string `json:"type,omitempty"`
+}
+
+// Rule unlocks an achievement once enough of a user's Pokémon events of
+// one kind add up. Count picks what is counted: every event, distinct
+// Pokémon or distinct species. Per, when set, splits the count by Pokémon,
+// type or generation and takes the best group. Complete replaces Target
+// with the number of species in the catalogue matching Filter.
+type Rule struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Event       string `json:"event"`
+	Count       string `json:"count"`
+	Per         string `json:"per,omitempty"`
+	Filter      Filter `json:"filter"`
+	Target      int    `json:"target,omitempty"`
+	Complete    bool   `json:"complete,omitempty"`
+}
+
+func (r Rule) validate() error {
+	if r.Id == "" {
+		return ErrorRuleIdRequired
+	}
+
+	if !events[r.Event] {
+		return fmt.Errorf("%s: %w", r.Id, ErrorRuleUnknownEvent)
+	}
+
+	switch r.Count {
+	case CountEvents, CountPokemon, CountSpecies:
+	default:
+		return fmt.Errorf("%s: %w", r.Id, ErrorRuleUnknownCount)
+	}
+
+	switch r.Per {
+	case "", PerPokemon, PerType, PerGeneration:
+	default:
+		return fmt.Errorf("%s: %w", r.Id, ErrorRuleUnknownPer)
+	}
+
+	if !r.Complete && r.Target <= 0 {
+		return fmt.Errorf("%s: %w", r.Id, ErrorRuleInvalidTarget)
+	}
+
+	return nil
+}
+
+func parseRules(data []byte) ([]Rule, error) {
+	var parsed []Rule
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(parsed))
+	for _, rule := range parsed {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+
+		if seen[rule.Id] {
+			return nil, fmt.Errorf("%s: %w", rule.Id, ErrorRuleDuplicate)
+		}
+		seen[rule.Id] = true
+	}
+
+	return parsed, nil
+}
+
+func mustParseRules(data []byte) []Rule {
+	parsed, err := parseRules(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+// LoadRules replaces the built-in achievements with the ones in a JSON
+// file. Nothing changes if the file is invalid.
+func LoadRules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseRules(data)
+	if err != nil {
+		return err
+	}
+
+	rules = parsed
+
+	return nil
+}
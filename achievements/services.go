@@ -0,0 +1,137 @@
+package achievements
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"sort"
+	"time"
+)
+
+// Evaluate checks every locked achievement that listens to event and
+// unlocks the ones the user now meets, inside the caller's transaction.
+func Evaluate(ctx context.Context, tx pgx.Tx, userId ulid.ULID, event string) ([]Badge, error) {
+	unlocked, err := findUnlocked(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var badges []Badge
+	for _, rule := range rules {
+		if rule.Event != event {
+			continue
+		}
+
+		if _, ok := unlocked[rule.Id]; ok {
+			continue
+		}
+
+		progress, target, err := measure(ctx, tx, rule, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		if target == 0 || progress < target {
+			continue
+		}
+
+		now := time.Now()
+		if err := saveUnlocked(ctx, tx, userId, rule.Id, now); err != nil {
+			return nil, err
+		}
+
+		badges = append(badges, newBadge(rule, now))
+	}
+
+	return badges, nil
+}
+
+func measure(ctx context.Context, tx pgx.Tx, rule Rule, userId ulid.ULID) (int, int, error) {
+	progress, err := findProgress(ctx, tx, rule, userId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	target, err := findTarget(ctx, tx, rule)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return progress, target, nil
+}
+
+// FindBadges lists a user's unlocked achievements, oldest first. Badges
+// whose rule has since been removed keep their id as their name.
+func FindBadges(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Badge, error) {
+	unlocked, err := findUnlocked(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		byId[rule.Id] = rule
+	}
+
+	badges := make([]Badge, 0, len(unlocked))
+	for id, at := range unlocked {
+		rule, ok := byId[id]
+		if !ok {
+			rule = Rule{Id: id, Name: id}
+		}
+
+		badges = append(badges, newBadge(rule, at))
+	}
+
+	sort.Slice(badges, func(i, k int) bool {
+		if badges[i].UnlockedAt.Equal(badges[k].UnlockedAt) {
+			return badges[i].Id < badges[k].Id
+		}
+		return badges[i].UnlockedAt.Before(badges[k].UnlockedAt)
+	})
+
+	return badges, nil
+}
+
+// listAchievements shows every current rule to the user with their
+// progress towards it.
+func listAchievements(ctx context.Context, userId ulid.ULID) ([]Achievement, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	unlocked, err := findUnlocked(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Achievement, 0, len(rules))
+	for _, rule := range rules {
+		progress, target, err := measure(ctx, tx, rule, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		achievement := Achievement{
+			Id:          rule.Id,
+			Name:        rule.Name,
+			Description: rule.Description,
+			Progress:    progress,
+			Target:      target,
+		}
+
+		if at, ok := unlocked[rule.Id]; ok {
+			achievement.UnlockedAt = &at
+		}
+
+		list = append(list, achievement)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+const (
+	ActionUserCreate        = "user.create"
+	ActionUserUpdate        = "user.update"
+	ActionUserDelete        = "user.delete"
+	ActionUserRestore       = "user.restore"
+	ActionUserPurge         = "user.purge"
+	ActionUserAnonymize     = "user.anonymize"
+	ActionUserRoleChange    = "user.role_change"
+	ActionUserPasswordReset = "user.password_reset"
+	ActionLoginSuccess      = "auth.login_success"
+	ActionLoginFailure      = "auth.login_failure"
+	ActionPokemonRelease    = "pokemon.release"
+	ActionPokemonRename     = "pokemon.rename"
+	ActionPokemonExperience = "pokemon.experience"
+	ActionPokemonEvolve     = "pokemon.evolve"
+	ActionInventoryGrant    = "inventory.grant"
+	ActionQuestReward       = "quest.reward"
+)
+
+const (
+	TargetUser        = "user"
+	TargetUserPokemon = "user_pokemon"
+)
+
+type Event struct {
+	Id         ulid.ULID
+	Action     string
+	ActorId    *ulid.ULID
+	TargetType string
+	TargetId   string
+	IP         string
+	RequestId  string
+	Metadata   map[string]interface{}
+	CreatedAt  time.Time
+}
+
+// NewEvent fills in the actor, client IP and request id from the request
+// context, so services only have to say what happened to what.
+func NewEvent(ctx context.Context, action, targetType, targetId string) (Event, error) {
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		Id:         id,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		IP:         clientIP(ctx),
+		RequestId:  middleware.GetReqID(ctx),
+		Metadata:   map[string]interface{}{},
+		CreatedAt:  time.Now(),
+	}
+
+	_, claims, _ := jwtauth.FromContext(ctx)
+	if s, ok := claims["user_id"].(string); ok {
+		if actorId, err := ulid.Parse(s); err == nil {
+			event.ActorId = &actorId
+		}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id         ulid.ULID              `json:"id"`
+		Action     string                 `json:"action"`
+		ActorId    *ulid.ULID             `json:"actor_id,omitempty"`
+		TargetType string                 `json:"target_type"`
+		TargetId   string                 `json:"target_id"`
+		IP         string                 `json:"ip,omitempty"`
+		RequestId  string                 `json:"request_id,omitempty"`
+		Metadata   map[string]interface{} `json:"metadata,omitempty"`
+		CreatedAt  time.Time              `json:"created_at"`
+	}
+
+	j.Id = e.Id
+	j.Action = e.Action
+	j.ActorId = e.ActorId
+	j.TargetType = e.TargetType
+	j.TargetId = e.TargetId
+	j.IP = e.IP
+	j.RequestId = e.RequestId
+	j.Metadata = e.Metadata
+	j.CreatedAt = e.CreatedAt
+
+	return json.Marshal(j)
+}
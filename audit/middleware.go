@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type contextKey struct{}
+
+var ipKey = contextKey{}
+
+// Middleware keeps the client address around for NewEvent. Mount it after
+// middleware.RealIP so proxies are already accounted for.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+
+		ctx := context.WithValue(r.Context(), ipKey, ip)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func clientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ipKey).(string)
+	return ip
+}
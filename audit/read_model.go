@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+type EventList struct {
+	Events     []Event    `json:"events"`
+	NextCursor *ulid.ULID `json:"next_cursor,omitempty"`
+}
+
+// EventFilter pages newest first with a keyset on the event id.
+type EventFilter struct {
+	From    time.Time
+	To      time.Time
+	ActorId ulid.ULID
+	Action  string
+	Limit   int
+	Cursor  ulid.ULID
+}
+
+func scanEvents(rows pgx.Rows) ([]Event, error) {
+	defer rows.Close()
+
+	events := []Event{}
+
+	for rows.Next() {
+		var event Event
+		var ip, requestId *string
+
+		if err := rows.Scan(&event.Id, &event.Action, &event.ActorId, &event.TargetType, &event.TargetId, &ip, &requestId, &event.Metadata, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if ip != nil {
+			event.IP = *ip
+		}
+		if requestId != nil {
+			event.RequestId = *requestId
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func findEvents(ctx context.Context, tx pgx.Tx, filter EventFilter) (EventList, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	var conds []string
+	var args []interface{}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conds = append(conds, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	if filter.ActorId != (ulid.ULID{}) {
+		args = append(args, filter.ActorId)
+		conds = append(conds, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conds = append(conds, fmt.Sprintf("action = $%d", len(args)))
+	}
+
+	if filter.Cursor != (ulid.ULID{}) {
+		args = append(args, filter.Cursor)
+		conds = append(conds, fmt.Sprintf("id < $%d", len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, limit+1)
+
+	rows, err := tx.Query(
+		ctx,
+		fmt.Sprintf(
+			"SELECT id, action, actor_id, target_type, target_id, ip, request_id, metadata, created_at FROM audit_events%s ORDER BY id DESC LIMIT $%d",
+			where, len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		return EventList{}, err
+	}
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return EventList{}, err
+	}
+
+	list := EventList{Events: events}
+
+	if len(events) > limit {
+		list.Events = events[:limit]
+		next := events[limit-1].Id
+		list.NextCursor = &next
+	}
+
+	return list, nil
+}
+
+// FindByUser returns every event the user either performed or was the target
+// of, oldest first.
+func FindByUser(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Event, error) {
+	rows, err := tx.Query(
+		ctx,
+		`SELECT id, action, actor_id, target_type, target_id, ip, request_id, metadata, created_at
+			FROM audit_events
+		 WHERE actor_id = $1 OR (target_type = $2 AND target_id = $3)
+		 ORDER BY id`,
+		userId, TargetUser, userId.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanEvents(rows)
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+// Record appends the event within tx, so it is only kept when the change it
+// describes is committed.
+func Record(ctx context.Context, tx pgx.Tx, event Event) error {
+	query := `INSERT INTO audit_events (id, action, actor_id, target_type, target_id, ip, request_id, metadata, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+
+	_, err := tx.Exec(ctx, query, event.Id, event.Action, event.ActorId, event.TargetType, event.TargetId, event.IP, event.RequestId, event.Metadata, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ScrubUser strips what identifies an erased user from the audit trail:
+// the client IP of every event they made or were the target of, failed
+// logins at their account included, and the username recorded when the
+// account was created. The events themselves stay so the trail keeps its
+// shape.
+func ScrubUser(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	query := `UPDATE audit_events SET ip = NULL, metadata = metadata - 'username'
+			   WHERE actor_id = $1
+				  OR (target_type = 'user' AND target_id = $2)`
+
+	_, err := tx.Exec(ctx, query, userId, userId.String())
+
+	return err
+}
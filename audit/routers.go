@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Use(helper.RoleMiddleware(helper.RoleAdmin))
+	r.Get("/", listEventsHandler)
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+func parseEventFilter(req *http.Request) (EventFilter, error) {
+	q := req.URL.Query()
+
+	filter := EventFilter{
+		Action: q.Get("action"),
+	}
+
+	if s := q.Get("from"); s != "" {
+		from, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return EventFilter{}, fmt.Errorf("invalid from: %v", err)
+		}
+		filter.From = from
+	}
+
+	if s := q.Get("to"); s != "" {
+		to, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return EventFilter{}, fmt.Errorf("invalid to: %v", err)
+		}
+		filter.To = to
+	}
+
+	if s := q.Get("actor_id"); s != "" {
+		actorId, err := ulid.Parse(s)
+		if err != nil {
+			return EventFilter{}, fmt.Errorf("invalid actor_id: %v", err)
+		}
+		filter.ActorId = actorId
+	}
+
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return EventFilter{}, fmt.Errorf("invalid limit: %s", s)
+		}
+		filter.Limit = limit
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		cursor, err := ulid.Parse(s)
+		if err != nil {
+			return EventFilter{}, fmt.Errorf("invalid cursor: %v", err)
+		}
+		filter.Cursor = cursor
+	}
+
+	return filter, nil
+}
+
+func listEventsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	filter, err := parseEventFilter(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := listEvents(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(events)
+	if err != nil {
+		return
+	}
+}
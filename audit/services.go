@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+func listEvents(ctx context.Context, filter EventFilter) (EventList, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return EventList{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	list, err := findEvents(ctx, tx, filter)
+	if err != nil {
+		return EventList{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return EventList{}, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,65 @@
+package battles
+
+import (
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+type Battle struct {
+	Id           ulid.ULID
+	ChallengerId ulid.ULID
+	OpponentId   ulid.ULID
+	Seed         int64
+	Sides        [2]Side
+	WinnerId     *ulid.ULID
+	Turns        int
+	Log          []Entry
+	Rewards      []Reward
+	CreatedAt    time.Time
+}
+
+// NewBattle fights the battle straight away: with the seed and both teams
+// fixed there is nothing left to decide.
+func NewBattle(challenger, opponent Side, seed int64) (Battle, error) {
+	if challenger.UserId == opponent.UserId {
+		return Battle{}, ErrorBattleWithSelf
+	}
+
+	if len(challenger.Team) == 0 || len(opponent.Team) == 0 {
+		return Battle{}, ErrorEmptyParty
+	}
+
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return Battle{}, err
+	}
+
+	battle := Battle{
+		Id:           id,
+		ChallengerId: challenger.UserId,
+		OpponentId:   opponent.UserId,
+		Seed:         seed,
+		Sides:        [2]Side{challenger, opponent},
+		CreatedAt:    time.Now(),
+	}
+
+	battle.apply(Simulate(seed, battle.Sides))
+
+	return battle, nil
+}
+
+func (b *Battle) apply(result Result) {
+	b.Turns = result.Turns
+	b.Log = result.Log
+	b.Rewards = result.Rewards
+	b.WinnerId = nil
+
+	if result.Winner >= 0 {
+		winner := b.Sides[result.Winner].UserId
+		b.WinnerId = &winner
+	}
+}
+
+func (b Battle) involves(userId ulid.ULID) bool {
+	return b.ChallengerId == userId || b.OpponentId == userId
+}
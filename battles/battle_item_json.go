@@ -0,0 +1,38 @@
+package battles
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (b Battle) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id           ulid.ULID  `json:"id"`
+		ChallengerId ulid.ULID  `json:"challenger_id"`
+		OpponentId   ulid.ULID  `json:"opponent_id"`
+		Seed         int64      `json:"seed"`
+		Sides        *[2]Side   `json:"sides,omitempty"`
+		WinnerId     *ulid.ULID `json:"winner_id"`
+		Turns        int        `json:"turns"`
+		Log          []Entry    `json:"log,omitempty"`
+		Rewards      []Reward   `json:"rewards,omitempty"`
+		CreatedAt    time.Time  `json:"created_at"`
+	}
+
+	j.Id = b.Id
+	j.ChallengerId = b.ChallengerId
+	j.OpponentId = b.OpponentId
+	j.Seed = b.Seed
+	// Listings leave out the teams and the log.
+	if b.Log != nil {
+		j.Sides = &b.Sides
+	}
+	j.WinnerId = b.WinnerId
+	j.Turns = b.Turns
+	j.Log = b.Log
+	j.Rewards = b.Rewards
+	j.CreatedAt = b.CreatedAt
+
+	return json.Marshal(j)
+}
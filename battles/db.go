@@ -0,0 +1,25 @@
+package battles
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	pool *pgxpool.Pool
+
+	ErrorBattleNotFound = errors.New("battle not found")
+	ErrorBattleWithSelf = errors.New("cannot battle yourself")
+	ErrorEmptyParty     = errors.New("both trainers need at least one pokemon in their party")
+	ErrorPartyChanged   = errors.New("a party changed while the battle was being set up, try again")
+)
+
+func SetPool(newPool *pgxpool.Pool) error {
+	if newPool == nil {
+		return errors.New("Cannot assign nil pool")
+	}
+
+	pool = newPool
+
+	return nil
+}
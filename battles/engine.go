@@ -0,0 +1,317 @@
+package battles
+
+import (
+	"github.com/oklog/ulid/v2"
+	"math/rand"
+	"mda/pokemon"
+)
+
+const maxTurns = 200
+
+// struggle is used by a Pokémon with no damaging move left to pick.
+var struggle = pokemon.Move{Name: "struggle", Power: 50, DamageClass: pokemon.DamagePhysical}
+
+const (
+	EventMove    = "move"
+	EventMiss    = "miss"
+	EventFaint   = "faint"
+	EventSwitch  = "switch"
+	EventVictory = "victory"
+	EventDraw    = "draw"
+)
+
+// Combatant is a snapshot of one Pokémon going into battle. Everything the
+// engine needs is in here, so a battle can be replayed without the network.
+type Combatant struct {
+	UserPokemonId  ulid.ULID      `json:"user_pokemon_id"`
+	PokemonId      int            `json:"pokemon_id"`
+	Name           string         `json:"name"`
+	Level          int            `json:"level"`
+	Types          []string       `json:"types"`
+	Stats          pokemon.Stats  `json:"stats"`
+	Moves          []pokemon.Move `json:"moves"`
+	BaseExperience int            `json:"base_experience"`
+	EffortYield    pokemon.Stats  `json:"effort_yield"`
+}
+
+type Side struct {
+	UserId ulid.ULID   `json:"user_id"`
+	Team   []Combatant `json:"team"`
+}
+
+// Entry is one line of the battle log.
+type Entry struct {
+	Turn          int        `json:"turn"`
+	Event         string     `json:"event"`
+	Side          int        `json:"side"`
+	Actor         *ulid.ULID `json:"actor,omitempty"`
+	Target        *ulid.ULID `json:"target,omitempty"`
+	Move          string     `json:"move,omitempty"`
+	Damage        int        `json:"damage,omitempty"`
+	Critical      bool       `json:"critical,omitempty"`
+	Effectiveness float64    `json:"effectiveness,omitempty"`
+	TargetHP      *int       `json:"target_hp,omitempty"`
+}
+
+// Reward is what one of the winner's Pokémon earned for the foes it
+// knocked out.
+type Reward struct {
+	UserPokemonId ulid.ULID     `json:"user_pokemon_id"`
+	Experience    int           `json:"experience"`
+	Effort        pokemon.Stats `json:"effort"`
+}
+
+// Result is the outcome of a simulated battle. Winner is the index of the
+// winning side, or -1 for a draw.
+type Result struct {
+	Winner  int      `json:"winner"`
+	Turns   int      `json:"turns"`
+	Log     []Entry  `json:"log"`
+	Rewards []Reward `json:"rewards"`
+}
+
+type fighter struct {
+	Combatant
+	hp       int
+	defeated []int
+}
+
+type battleSide struct {
+	fighters []*fighter
+	active   int
+}
+
+func (s *battleSide) current() *fighter {
+	return s.fighters[s.active]
+}
+
+// next sends out the first Pokémon still standing.
+func (s *battleSide) next() bool {
+	for i, f := range s.fighters {
+		if f.hp > 0 {
+			s.active = i
+			return true
+		}
+	}
+
+	return false
+}
+
+// Simulate plays a battle between two sides to the end. It draws every
+// random number from a source seeded with seed, so the same seed and sides
+// always give the same battle.
+func Simulate(seed int64, sides [2]Side) Result {
+	rng := rand.New(rand.NewSource(seed))
+	result := Result{Winner: -1, Log: []Entry{}, Rewards: []Reward{}}
+
+	var state [2]*battleSide
+	for i, side := range sides {
+		state[i] = &battleSide{}
+		for _, c := range side.Team {
+			state[i].fighters = append(state[i].fighters, &fighter{Combatant: c, hp: c.Stats[pokemon.StatHP]})
+		}
+	}
+
+	for i := range state {
+		if len(state[i].fighters) == 0 || !state[i].next() {
+			result.Winner = 1 - i
+			result.Log = append(result.Log, Entry{Event: EventVictory, Side: 1 - i})
+			return result
+		}
+
+		id := state[i].current().UserPokemonId
+		result.Log = append(result.Log, Entry{Event: EventSwitch, Side: i, Actor: &id})
+	}
+
+	for turn := 1; turn <= maxTurns; turn++ {
+		result.Turns = turn
+
+		var moves [2]pokemon.Move
+		for i := range state {
+			moves[i] = chooseMove(state[i].current(), state[1-i].current())
+		}
+
+		for _, i := range turnOrder(rng, state, moves) {
+			attacker := state[i].current()
+			if attacker.hp <= 0 {
+				continue
+			}
+
+			defenderSide := state[1-i]
+			defender := defenderSide.current()
+
+			entry := attack(rng, attacker, defender, moves[i])
+			entry.Turn = turn
+			entry.Side = i
+			result.Log = append(result.Log, entry)
+
+			if defender.hp > 0 {
+				continue
+			}
+
+			attacker.defeated = append(attacker.defeated, defenderSide.active)
+			targetId := defender.UserPokemonId
+			result.Log = append(result.Log, Entry{Turn: turn, Event: EventFaint, Side: 1 - i, Actor: &targetId})
+
+			if !defenderSide.next() {
+				result.Winner = i
+				result.Log = append(result.Log, Entry{Turn: turn, Event: EventVictory, Side: i})
+				result.Rewards = rewards(state[i], state[1-i])
+				return result
+			}
+
+			nextId := defenderSide.current().UserPokemonId
+			result.Log = append(result.Log, Entry{Turn: turn, Event: EventSwitch, Side: 1 - i, Actor: &nextId})
+
+			// The Pokémon sent in does not get to act this turn.
+			break
+		}
+	}
+
+	result.Log = append(result.Log, Entry{Turn: result.Turns, Event: EventDraw, Side: -1})
+
+	return result
+}
+
+// chooseMove picks the damaging move with the best expected damage against
+// the current foe, falling back to struggle.
+func chooseMove(attacker, defender *fighter) pokemon.Move {
+	best := struggle
+	bestScore := 0.0
+
+	for _, move := range attacker.Moves {
+		if move.Power <= 0 || move.DamageClass == pokemon.DamageStatus {
+			continue
+		}
+
+		score := float64(move.Power) * pokemon.Effectiveness(move.Type, defender.Types) * stab(attacker, move)
+		if move.Accuracy > 0 {
+			score *= float64(move.Accuracy) / 100
+		}
+
+		if score > bestScore {
+			best, bestScore = move, score
+		}
+	}
+
+	return best
+}
+
+// turnOrder sorts the two sides by move priority, then speed, with a coin
+// flip on a speed tie.
+func turnOrder(rng *rand.Rand, state [2]*battleSide, moves [2]pokemon.Move) [2]int {
+	if moves[0].Priority != moves[1].Priority {
+		if moves[0].Priority > moves[1].Priority {
+			return [2]int{0, 1}
+		}
+		return [2]int{1, 0}
+	}
+
+	speed0 := state[0].current().Stats[pokemon.StatSpeed]
+	speed1 := state[1].current().Stats[pokemon.StatSpeed]
+
+	if speed0 > speed1 || (speed0 == speed1 && rng.Intn(2) == 0) {
+		return [2]int{0, 1}
+	}
+
+	return [2]int{1, 0}
+}
+
+func attack(rng *rand.Rand, attacker, defender *fighter, move pokemon.Move) Entry {
+	actorId, targetId := attacker.UserPokemonId, defender.UserPokemonId
+	entry := Entry{Event: EventMove, Actor: &actorId, Target: &targetId, Move: move.Name}
+
+	if move.Accuracy > 0 && rng.Intn(100) >= move.Accuracy {
+		entry.Event = EventMiss
+		return entry
+	}
+
+	critical := rng.Intn(24) == 0
+	roll := 85 + rng.Intn(16)
+
+	damage, effectiveness := calculateDamage(attacker, defender, move, critical, roll)
+
+	defender.hp -= damage
+	if defender.hp < 0 {
+		defender.hp = 0
+	}
+
+	hp := defender.hp
+	entry.Damage = damage
+	entry.Critical = critical && damage > 0
+	entry.Effectiveness = effectiveness
+	entry.TargetHP = &hp
+
+	return entry
+}
+
+// calculateDamage is the main series damage formula: a base from level,
+// power and the attacking and defending stats, scaled by critical hits,
+// the random roll, same type attack bonus and type effectiveness.
+func calculateDamage(attacker, defender *fighter, move pokemon.Move, critical bool, roll int) (int, float64) {
+	attackStat, defenseStat := pokemon.StatAttack, pokemon.StatDefense
+	if move.DamageClass == pokemon.DamageSpecial {
+		attackStat, defenseStat = pokemon.StatSpecialAttack, pokemon.StatSpecialDefense
+	}
+
+	a := attacker.Stats[attackStat]
+	d := defender.Stats[defenseStat]
+	if d < 1 {
+		d = 1
+	}
+
+	base := (2*attacker.Level/5+2)*move.Power*a/d/50 + 2
+
+	effectiveness := pokemon.Effectiveness(move.Type, defender.Types)
+	if effectiveness == 0 {
+		return 0, 0
+	}
+
+	modifier := float64(roll) / 100 * stab(attacker, move) * effectiveness
+	if critical {
+		modifier *= 1.5
+	}
+
+	damage := int(float64(base) * modifier)
+	if damage < 1 {
+		damage = 1
+	}
+
+	return damage, effectiveness
+}
+
+func stab(attacker *fighter, move pokemon.Move) float64 {
+	for _, t := range attacker.Types {
+		if t == move.Type {
+			return 1.5
+		}
+	}
+
+	return 1
+}
+
+// rewards gives each of the winner's Pokémon experience and effort values
+// for every foe it knocked out, using the classic base_experience * level / 7.
+func rewards(winner, loser *battleSide) []Reward {
+	rewards := []Reward{}
+
+	for _, f := range winner.fighters {
+		if len(f.defeated) == 0 {
+			continue
+		}
+
+		reward := Reward{UserPokemonId: f.UserPokemonId}
+		for _, idx := range f.defeated {
+			foe := loser.fighters[idx]
+			reward.Experience += foe.BaseExperience * foe.Level / 7
+
+			for stat := range reward.Effort {
+				reward.Effort[stat] += foe.EffortYield[stat]
+			}
+		}
+
+		rewards = append(rewards, reward)
+	}
+
+	return rewards
+}
@@ -0,0 +1,128 @@
+package battles
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"mda/pokemon"
+)
+
+var (
+	tackle      = pokemon.Move{Name: "tackle", Type: "normal", Power: 40, Accuracy: 100, DamageClass: pokemon.DamagePhysical}
+	ember       = pokemon.Move{Name: "ember", Type: "fire", Power: 40, Accuracy: 100, DamageClass: pokemon.DamageSpecial}
+	waterGun    = pokemon.Move{Name: "water-gun", Type: "water", Power: 40, Accuracy: 100, DamageClass: pokemon.DamageSpecial}
+	quickAttack = pokemon.Move{Name: "quick-attack", Type: "normal", Power: 40, Accuracy: 100, Priority: 1, DamageClass: pokemon.DamagePhysical}
+)
+
+func combatant(name string, types []string, stats pokemon.Stats, moves ...pokemon.Move) Combatant {
+	return Combatant{
+		UserPokemonId:  ulid.Make(),
+		Name:           name,
+		Level:          10,
+		Types:          types,
+		Stats:          stats,
+		Moves:          moves,
+		BaseExperience: 60,
+	}
+}
+
+func testSides() [2]Side {
+	return [2]Side{
+		{UserId: ulid.Make(), Team: []Combatant{
+			combatant("charmander", []string{"fire"}, pokemon.Stats{30, 15, 13, 16, 14, 17}, ember, tackle),
+			combatant("rattata", []string{"normal"}, pokemon.Stats{28, 16, 12, 10, 12, 20}, tackle),
+		}},
+		{UserId: ulid.Make(), Team: []Combatant{
+			combatant("squirtle", []string{"water"}, pokemon.Stats{31, 14, 17, 14, 16, 13}, waterGun, tackle),
+			combatant("pidgey", []string{"normal", "flying"}, pokemon.Stats{29, 14, 13, 12, 12, 18}, tackle),
+		}},
+	}
+}
+
+func TestSimulateIsDeterministic(t *testing.T) {
+	sides := testSides()
+
+	first := Simulate(42, sides)
+	second := Simulate(42, sides)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed gave different battles:\n%+v\n%+v", first, second)
+	}
+
+	if first.Winner != 0 && first.Winner != 1 {
+		t.Fatalf("expected a winner, got %d after %d turns", first.Winner, first.Turns)
+	}
+}
+
+func TestImmuneTargetTakesNoDamage(t *testing.T) {
+	attacker := &fighter{Combatant: combatant("rattata", []string{"normal"}, pokemon.Stats{28, 16, 12, 10, 12, 20}, tackle), hp: 28}
+	defender := &fighter{Combatant: combatant("gastly", []string{"ghost", "poison"}, pokemon.Stats{25, 10, 9, 30, 10, 25}), hp: 25}
+
+	damage, effectiveness := calculateDamage(attacker, defender, tackle, true, 100)
+	if damage != 0 || effectiveness != 0 {
+		t.Fatalf("normal move on ghost: got damage %d, effectiveness %v", damage, effectiveness)
+	}
+
+	entry := attack(rand.New(rand.NewSource(1)), attacker, defender, tackle)
+	if entry.Damage != 0 || defender.hp != 25 {
+		t.Fatalf("immune defender lost hp: damage %d, hp %d", entry.Damage, defender.hp)
+	}
+
+	if entry.Critical {
+		t.Fatal("a hit for no damage should not be reported as critical")
+	}
+}
+
+func TestTurnOrder(t *testing.T) {
+	slow := &battleSide{fighters: []*fighter{{Combatant: combatant("slow", nil, pokemon.Stats{30, 10, 10, 10, 10, 5})}}}
+	fast := &battleSide{fighters: []*fighter{{Combatant: combatant("fast", nil, pokemon.Stats{30, 10, 10, 10, 10, 50})}}}
+	rng := rand.New(rand.NewSource(1))
+
+	cases := []struct {
+		name  string
+		state [2]*battleSide
+		moves [2]pokemon.Move
+		want  [2]int
+	}{
+		{"faster goes first", [2]*battleSide{slow, fast}, [2]pokemon.Move{tackle, tackle}, [2]int{1, 0}},
+		{"faster goes first on side 0", [2]*battleSide{fast, slow}, [2]pokemon.Move{tackle, tackle}, [2]int{0, 1}},
+		{"priority beats speed", [2]*battleSide{slow, fast}, [2]pokemon.Move{quickAttack, tackle}, [2]int{0, 1}},
+		{"priority beats speed on side 1", [2]*battleSide{fast, slow}, [2]pokemon.Move{tackle, quickAttack}, [2]int{1, 0}},
+	}
+
+	for _, c := range cases {
+		if got := turnOrder(rng, c.state, c.moves); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSimulateDrawsAfterMaxTurns(t *testing.T) {
+	// Both sides shrug off every hit, so nobody can faint in time.
+	wall := pokemon.Stats{100000, 1, 1000, 1, 1000, 10}
+	sides := [2]Side{
+		{UserId: ulid.Make(), Team: []Combatant{combatant("wall-a", []string{"normal"}, wall, tackle)}},
+		{UserId: ulid.Make(), Team: []Combatant{combatant("wall-b", []string{"normal"}, wall, tackle)}},
+	}
+
+	result := Simulate(7, sides)
+
+	if result.Winner != -1 {
+		t.Fatalf("expected a draw, side %d won", result.Winner)
+	}
+
+	if result.Turns != maxTurns {
+		t.Fatalf("expected %d turns, got %d", maxTurns, result.Turns)
+	}
+
+	last := result.Log[len(result.Log)-1]
+	if last.Event != EventDraw {
+		t.Fatalf("expected the log to end in a draw, got %q", last.Event)
+	}
+
+	if len(result.Rewards) != 0 {
+		t.Fatalf("a draw should give no rewards, got %+v", result.Rewards)
+	}
+}
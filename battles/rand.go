@@ -0,0 +1,28 @@
+package battles
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngMu sync.Mutex
+)
+
+// SetRandSource replaces the source that seeds new battles. The battles
+// themselves only ever use their own stored seed.
+func SetRandSource(source rand.Source) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	rng = rand.New(source)
+}
+
+func newSeed() int64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	return rng.Int63()
+}
@@ -0,0 +1,67 @@
+package battles
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+const battleColumns = `id, challenger_id, opponent_id, seed, sides, winner_id, turns, log, rewards, created_at`
+
+func scanBattle(row pgx.Row) (Battle, error) {
+	var b Battle
+	err := row.Scan(&b.Id, &b.ChallengerId, &b.OpponentId, &b.Seed, &b.Sides, &b.WinnerId, &b.Turns, &b.Log, &b.Rewards, &b.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Battle{}, ErrorBattleNotFound
+		}
+		return Battle{}, err
+	}
+
+	return b, nil
+}
+
+func findBattleById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (Battle, error) {
+	query := `SELECT ` + battleColumns + `
+				FROM battles
+			 WHERE id = $1`
+
+	return scanBattle(tx.QueryRow(ctx, query, id))
+}
+
+// findBattlesByUserId lists a trainer's battles, newest first, without the
+// teams and the log, which can be fetched one battle at a time.
+func findBattlesByUserId(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Battle, error) {
+	query := `SELECT id, challenger_id, opponent_id, seed, winner_id, turns, created_at
+				FROM battles
+			 WHERE challenger_id = $1 OR opponent_id = $1
+			 ORDER BY id DESC`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	battles := []Battle{}
+	for rows.Next() {
+		var b Battle
+		if err := rows.Scan(&b.Id, &b.ChallengerId, &b.OpponentId, &b.Seed, &b.WinnerId, &b.Turns, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		battles = append(battles, b)
+	}
+
+	return battles, rows.Err()
+}
+
+func saveBattle(ctx context.Context, tx pgx.Tx, b Battle) error {
+	query := `INSERT INTO battles (` + battleColumns + `)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := tx.Exec(ctx, query, b.Id, b.ChallengerId, b.OpponentId, b.Seed, b.Sides, b.WinnerId, b.Turns, b.Log, b.Rewards, b.CreatedAt)
+
+	return err
+}
@@ -0,0 +1,165 @@
+package battles
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"mda/pokemon"
+	"mda/users"
+	"net/http"
+)
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Get("/", listBattlesHandler)
+	r.Post("/", startBattleHandler)
+	r.Get("/{id}", getBattleHandler)
+	r.Get("/{id}/replay", replayBattleHandler)
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+func writeBattleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorBattleNotFound), errors.Is(err, users.ErrorUserNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, users.ErrorUserBlocked), errors.Is(err, users.ErrorNotAllowed):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, ErrorBattleWithSelf), errors.Is(err, ErrorEmptyParty):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, ErrorPartyChanged):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, pokemon.ErrorPokemonNotFound), errors.Is(err, pokemon.ErrorMoveNotFound):
+		writeError(w, http.StatusBadGateway, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func listBattlesHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	battles, err := listBattles(ctx, userId)
+	if err != nil {
+		writeBattleError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, battles)
+}
+
+func startBattleHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		OpponentId ulid.ULID `json:"opponent_id"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	battle, err := startBattle(ctx, userId, j.OpponentId)
+	if err != nil {
+		writeBattleError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, battle)
+}
+
+func getBattleHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	battle, err := getBattle(ctx, id, userId)
+	if err != nil {
+		writeBattleError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, battle)
+}
+
+func replayBattleHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	result, err := replayBattle(ctx, id, userId)
+	if err != nil {
+		writeBattleError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
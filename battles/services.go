@@ -0,0 +1,299 @@
+package battles
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"mda/pokemon"
+	"mda/realtime"
+	"mda/users"
+	"mda/userspokemon"
+	"sync"
+)
+
+// FinishedHandler reacts to a battle inside the transaction that stored it.
+// Returning an error rolls the battle back.
+type FinishedHandler func(ctx context.Context, tx pgx.Tx, battle Battle) error
+
+var (
+	handlers   []FinishedHandler
+	handlersMu sync.RWMutex
+)
+
+// Subscribe registers a handler for every finished battle.
+func Subscribe(handler FinishedHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers = append(handlers, handler)
+}
+
+func notifyFinished(ctx context.Context, tx pgx.Tx, battle Battle) error {
+	handlersMu.RLock()
+	subscribed := append([]FinishedHandler(nil), handlers...)
+	handlersMu.RUnlock()
+
+	for _, handler := range subscribed {
+		if err := handler(ctx, tx, battle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findParties reads both trainers' parties in a short transaction of its
+// own, so their stats and moves can be resolved before anything is locked.
+func findParties(ctx context.Context, challengerId, opponentId ulid.ULID) ([]userspokemon.UserPokemon, []userspokemon.UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = users.CheckAccess(ctx, tx, challengerId, opponentId, users.AspectBattles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challenger, err := userspokemon.FindParty(ctx, tx, challengerId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opponent, err := userspokemon.FindParty(ctx, tx, opponentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return challenger, opponent, nil
+}
+
+// buildSide turns a party snapshot into a side with stats and moves
+// resolved, so the engine itself never touches the network.
+func buildSide(userId ulid.ULID, party []userspokemon.UserPokemon) (Side, error) {
+	side := Side{UserId: userId, Team: []Combatant{}}
+	for _, userPokemon := range party {
+		profile, err := pokemon.FindProfile(userPokemon.PokemonId)
+		if err != nil {
+			return Side{}, err
+		}
+
+		moves, err := pokemon.FindMoveset(profile, userPokemon.Level)
+		if err != nil {
+			return Side{}, err
+		}
+
+		side.Team = append(side.Team, Combatant{
+			UserPokemonId:  userPokemon.Id,
+			PokemonId:      userPokemon.PokemonId,
+			Name:           userPokemon.Nickname,
+			Level:          userPokemon.Level,
+			Types:          profile.Types,
+			Stats:          pokemon.CalculateStats(profile.BaseStats, userPokemon.IVs, userPokemon.EVs, userPokemon.Level, userPokemon.Nature),
+			Moves:          moves,
+			BaseExperience: profile.BaseExperience,
+			EffortYield:    profile.EffortYield,
+		})
+	}
+
+	return side, nil
+}
+
+// sameParty reports whether a party still fields the Pokémon of an
+// earlier snapshot, in the same slots and with the same stats.
+func sameParty(party, snapshot []userspokemon.UserPokemon) bool {
+	if len(party) != len(snapshot) {
+		return false
+	}
+
+	for i, userPokemon := range party {
+		was := snapshot[i]
+		if userPokemon.Id != was.Id || userPokemon.PokemonId != was.PokemonId || userPokemon.Level != was.Level ||
+			userPokemon.IVs != was.IVs || userPokemon.EVs != was.EVs || userPokemon.Nature != was.Nature {
+			return false
+		}
+	}
+
+	return true
+}
+
+// startBattle pits the challenger's party against the opponent's and hands
+// out the winner's experience in the same transaction. The parties are
+// resolved against PokeAPI first; the transaction then locks both and
+// checks nothing changed in between.
+func startBattle(ctx context.Context, challengerId, opponentId ulid.ULID) (Battle, error) {
+	if challengerId == opponentId {
+		return Battle{}, ErrorBattleWithSelf
+	}
+
+	challengerParty, opponentParty, err := findParties(ctx, challengerId, opponentId)
+	if err != nil {
+		return Battle{}, err
+	}
+
+	challenger, err := buildSide(challengerId, challengerParty)
+	if err != nil {
+		return Battle{}, err
+	}
+
+	opponent, err := buildSide(opponentId, opponentParty)
+	if err != nil {
+		return Battle{}, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Battle{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = users.CheckAccess(ctx, tx, challengerId, opponentId, users.AspectBattles)
+	if err != nil {
+		return Battle{}, err
+	}
+
+	// Lock both trainers in a fixed order so two crossing battles cannot
+	// deadlock on each other's party.
+	trainers := []ulid.ULID{challengerId, opponentId}
+	if trainers[1].Compare(trainers[0]) < 0 {
+		trainers[0], trainers[1] = trainers[1], trainers[0]
+	}
+
+	for _, trainerId := range trainers {
+		if err := userspokemon.LockParty(ctx, tx, trainerId); err != nil {
+			return Battle{}, err
+		}
+	}
+
+	snapshots := map[ulid.ULID][]userspokemon.UserPokemon{challengerId: challengerParty, opponentId: opponentParty}
+	for trainerId, snapshot := range snapshots {
+		party, err := userspokemon.FindParty(ctx, tx, trainerId)
+		if err != nil {
+			return Battle{}, err
+		}
+
+		if !sameParty(party, snapshot) {
+			return Battle{}, ErrorPartyChanged
+		}
+	}
+
+	battle, err := NewBattle(challenger, opponent, newSeed())
+	if err != nil {
+		return Battle{}, err
+	}
+
+	err = saveBattle(ctx, tx, battle)
+	if err != nil {
+		return Battle{}, err
+	}
+
+	if err := notifyFinished(ctx, tx, battle); err != nil {
+		return Battle{}, err
+	}
+
+	for _, reward := range battle.Rewards {
+		if reward.Experience > 0 {
+			_, _, err = userspokemon.AwardExperience(ctx, tx, reward.UserPokemonId, reward.Experience)
+			if err != nil {
+				return Battle{}, err
+			}
+		}
+
+		_, err = userspokemon.AwardEffort(ctx, tx, reward.UserPokemonId, reward.Effort)
+		if err != nil {
+			return Battle{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Battle{}, err
+	}
+
+	notifyBattle(battle)
+
+	return battle, nil
+}
+
+// notifyBattle streams the battle to both trainers: every move of the other
+// side, then the outcome.
+func notifyBattle(battle Battle) {
+	for i, userId := range []ulid.ULID{battle.ChallengerId, battle.OpponentId} {
+		for _, entry := range battle.Log {
+			if entry.Side != 1-i || (entry.Event != EventMove && entry.Event != EventMiss) {
+				continue
+			}
+
+			err := realtime.Publish(userId, realtime.EventBattleMove, struct {
+				BattleId ulid.ULID `json:"battle_id"`
+				Entry
+			}{battle.Id, entry})
+			if err != nil {
+				log.Error().Err(err).Msg("failed to publish battle event")
+			}
+		}
+
+		err := realtime.Publish(userId, realtime.EventBattleFinished, map[string]interface{}{
+			"battle_id": battle.Id,
+			"winner_id": battle.WinnerId,
+			"turns":     battle.Turns,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to publish battle event")
+		}
+	}
+}
+
+func listBattles(ctx context.Context, userId ulid.ULID) ([]Battle, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	battles, err := findBattlesByUserId(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return battles, nil
+}
+
+func getBattle(ctx context.Context, id, userId ulid.ULID) (Battle, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Battle{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	battle, err := findBattleById(ctx, tx, id)
+	if err != nil {
+		return Battle{}, err
+	}
+
+	if !battle.involves(userId) {
+		return Battle{}, ErrorBattleNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Battle{}, err
+	}
+
+	return battle, nil
+}
+
+// replayBattle runs a stored battle through the engine again from its seed
+// and teams. The result matches the stored log unless the engine changed.
+func replayBattle(ctx context.Context, id, userId ulid.ULID) (Result, error) {
+	battle, err := getBattle(ctx, id, userId)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Simulate(battle.Seed, battle.Sides), nil
+}
@@ -0,0 +1,46 @@
+package battles
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"mda/userspokemon"
+)
+
+func TestSameParty(t *testing.T) {
+	snapshot := []userspokemon.UserPokemon{
+		{Id: ulid.Make(), PokemonId: 4, Level: 12, Nature: "bold"},
+		{Id: ulid.Make(), PokemonId: 19, Level: 8, Nature: "hasty"},
+	}
+
+	copyOf := func() []userspokemon.UserPokemon {
+		return append([]userspokemon.UserPokemon(nil), snapshot...)
+	}
+
+	if !sameParty(copyOf(), snapshot) {
+		t.Fatal("an unchanged party was reported as changed")
+	}
+
+	leveled := copyOf()
+	leveled[1].Level++
+
+	trained := copyOf()
+	trained[0].EVs[0] += 4
+
+	swapped := copyOf()
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+
+	cases := map[string][]userspokemon.UserPokemon{
+		"leveled up":    leveled,
+		"trained":       trained,
+		"reordered":     swapped,
+		"member left":   snapshot[:1],
+		"member joined": append(copyOf(), userspokemon.UserPokemon{Id: ulid.Make(), PokemonId: 1, Level: 5}),
+	}
+
+	for name, party := range cases {
+		if sameParty(party, snapshot) {
+			t.Errorf("%s: change went unnoticed", name)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"io"
+	"mda/inventory"
+	"mda/mailer"
 	"os"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -94,20 +97,295 @@ func (l *listenConfig) loadFromEnv() {
 	loadEnvUint("KAD_LISTEN_PORT", &l.Port)
 }
 
+type mailConfig struct {
+	Driver string `yaml:"driver" json:"driver"`
+
+	Host     string `yaml:"host" json:"host"`
+	Port     uint   `yaml:"port" json:"port"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	From     string `yaml:"from" json:"from"`
+
+	ResetTokenTTLMinutes uint `yaml:"reset_token_ttl_minutes" json:"reset_token_ttl_minutes"`
+}
+
+func defaultMailConfig() mailConfig {
+	return mailConfig{
+		Driver:               "log",
+		Host:                 "localhost",
+		Port:                 25,
+		From:                 "no-reply@localhost",
+		ResetTokenTTLMinutes: 60,
+	}
+}
+
+func (m *mailConfig) loadFromEnv() {
+	loadEnvStr("KAD_MAIL_DRIVER", &m.Driver)
+	loadEnvStr("KAD_MAIL_HOST", &m.Host)
+	loadEnvUint("KAD_MAIL_PORT", &m.Port)
+	loadEnvStr("KAD_MAIL_USERNAME", &m.Username)
+	loadEnvStr("KAD_MAIL_PASSWORD", &m.Password)
+	loadEnvStr("KAD_MAIL_FROM", &m.From)
+	loadEnvUint("KAD_MAIL_RESET_TTL", &m.ResetTokenTTLMinutes)
+}
+
+func (m mailConfig) Mailer() mailer.Mailer {
+	if m.Driver == "smtp" {
+		return mailer.NewSMTPMailer(m.Host, m.Port, m.Username, m.Password, m.From)
+	}
+
+	return mailer.NewLogMailer()
+}
+
+type usersConfig struct {
+	PurgeAfterDays       uint `yaml:"purge_after_days" json:"purge_after_days"`
+	PurgeIntervalMinutes uint `yaml:"purge_interval_minutes" json:"purge_interval_minutes"`
+}
+
+func defaultUsersConfig() usersConfig {
+	return usersConfig{
+		PurgeAfterDays:       30,
+		PurgeIntervalMinutes: 60,
+	}
+}
+
+func (u *usersConfig) loadFromEnv() {
+	loadEnvUint("KAD_USERS_PURGE_AFTER_DAYS", &u.PurgeAfterDays)
+	loadEnvUint("KAD_USERS_PURGE_INTERVAL", &u.PurgeIntervalMinutes)
+}
+
+type inventoryConfig struct {
+	DailyPokeBalls  uint `yaml:"daily_poke_balls" json:"daily_poke_balls"`
+	DailyGreatBalls uint `yaml:"daily_great_balls" json:"daily_great_balls"`
+	DailyUltraBalls uint `yaml:"daily_ultra_balls" json:"daily_ultra_balls"`
+}
+
+func defaultInventoryConfig() inventoryConfig {
+	return inventoryConfig{
+		DailyPokeBalls: 5,
+	}
+}
+
+func (i *inventoryConfig) loadFromEnv() {
+	loadEnvUint("KAD_INVENTORY_DAILY_POKE_BALLS", &i.DailyPokeBalls)
+	loadEnvUint("KAD_INVENTORY_DAILY_GREAT_BALLS", &i.DailyGreatBalls)
+	loadEnvUint("KAD_INVENTORY_DAILY_ULTRA_BALLS", &i.DailyUltraBalls)
+}
+
+func (i inventoryConfig) DailyAllowance() []inventory.Stack {
+	return []inventory.Stack{
+		{Item: inventory.PokeBall, Quantity: int(i.DailyPokeBalls)},
+		{Item: inventory.GreatBall, Quantity: int(i.DailyGreatBalls)},
+		{Item: inventory.UltraBall, Quantity: int(i.DailyUltraBalls)},
+	}
+}
+
+type encountersConfig struct {
+	Attempts     uint `yaml:"attempts" json:"attempts"`
+	TTLMinutes   uint `yaml:"ttl_minutes" json:"ttl_minutes"`
+	MaxSpeciesId uint `yaml:"max_species_id" json:"max_species_id"`
+}
+
+func defaultEncountersConfig() encountersConfig {
+	return encountersConfig{
+		Attempts:     3,
+		TTLMinutes:   15,
+		MaxSpeciesId: 1025,
+	}
+}
+
+func (e *encountersConfig) loadFromEnv() {
+	loadEnvUint("KAD_ENCOUNTERS_ATTEMPTS", &e.Attempts)
+	loadEnvUint("KAD_ENCOUNTERS_TTL", &e.TTLMinutes)
+	loadEnvUint("KAD_ENCOUNTERS_MAX_SPECIES_ID", &e.MaxSpeciesId)
+}
+
+type catchesConfig struct {
+	ShinyOdds         uint `yaml:"shiny_odds" json:"shiny_odds"`
+	HiddenAbilityOdds uint `yaml:"hidden_ability_odds" json:"hidden_ability_odds"`
+}
+
+func defaultCatchesConfig() catchesConfig {
+	return catchesConfig{
+		ShinyOdds:         4096,
+		HiddenAbilityOdds: 50,
+	}
+}
+
+func (c *catchesConfig) loadFromEnv() {
+	loadEnvUint("KAD_CATCHES_SHINY_ODDS", &c.ShinyOdds)
+	loadEnvUint("KAD_CATCHES_HIDDEN_ABILITY_ODDS", &c.HiddenAbilityOdds)
+}
+
+type tradesConfig struct {
+	TTLHours uint `yaml:"ttl_hours" json:"ttl_hours"`
+}
+
+func defaultTradesConfig() tradesConfig {
+	return tradesConfig{
+		TTLHours: 72,
+	}
+}
+
+func (t *tradesConfig) loadFromEnv() {
+	loadEnvUint("KAD_TRADES_TTL", &t.TTLHours)
+}
+
+type boxesConfig struct {
+	DefaultCapacity uint `yaml:"default_capacity" json:"default_capacity"`
+}
+
+func defaultBoxesConfig() boxesConfig {
+	return boxesConfig{
+		DefaultCapacity: 30,
+	}
+}
+
+func (b *boxesConfig) loadFromEnv() {
+	loadEnvUint("KAD_BOXES_DEFAULT_CAPACITY", &b.DefaultCapacity)
+}
+
+type realtimeConfig struct {
+	HistorySize         uint `yaml:"history_size" json:"history_size"`
+	SendQueue           uint `yaml:"send_queue" json:"send_queue"`
+	PingIntervalSeconds uint `yaml:"ping_interval_seconds" json:"ping_interval_seconds"`
+}
+
+func defaultRealtimeConfig() realtimeConfig {
+	return realtimeConfig{
+		HistorySize:         100,
+		SendQueue:           64,
+		PingIntervalSeconds: 30,
+	}
+}
+
+func (r *realtimeConfig) loadFromEnv() {
+	loadEnvUint("KAD_REALTIME_HISTORY_SIZE", &r.HistorySize)
+	loadEnvUint("KAD_REALTIME_SEND_QUEUE", &r.SendQueue)
+	loadEnvUint("KAD_REALTIME_PING_INTERVAL", &r.PingIntervalSeconds)
+}
+
+type nicknamesConfig struct {
+	BlockedWords []string `yaml:"blocked_words" json:"blocked_words"`
+}
+
+func defaultNicknamesConfig() nicknamesConfig {
+	return nicknamesConfig{
+		BlockedWords: []string{},
+	}
+}
+
+func (n *nicknamesConfig) loadFromEnv() {
+	var words string
+	loadEnvStr("KAD_NICKNAMES_BLOCKED_WORDS", &words)
+
+	if words != "" {
+		n.BlockedWords = strings.Split(words, ",")
+	}
+}
+
+type pokedexConfig struct {
+	SyncIntervalHours uint `yaml:"sync_interval_hours" json:"sync_interval_hours"`
+}
+
+func defaultPokedexConfig() pokedexConfig {
+	return pokedexConfig{
+		SyncIntervalHours: 168,
+	}
+}
+
+func (p *pokedexConfig) loadFromEnv() {
+	loadEnvUint("KAD_POKEDEX_SYNC_INTERVAL", &p.SyncIntervalHours)
+}
+
+type achievementsConfig struct {
+	RulesFile string `yaml:"rules_file" json:"rules_file"`
+}
+
+func defaultAchievementsConfig() achievementsConfig {
+	return achievementsConfig{
+		RulesFile: "",
+	}
+}
+
+func (a *achievementsConfig) loadFromEnv() {
+	loadEnvStr("KAD_ACHIEVEMENTS_RULES_FILE", &a.RulesFile)
+}
+
+type questsConfig struct {
+	DailyCount               uint `yaml:"daily_count" json:"daily_count"`
+	WeeklyCount              uint `yaml:"weekly_count" json:"weekly_count"`
+	SchedulerIntervalMinutes uint `yaml:"scheduler_interval_minutes" json:"scheduler_interval_minutes"`
+}
+
+func defaultQuestsConfig() questsConfig {
+	return questsConfig{
+		DailyCount:               3,
+		WeeklyCount:              2,
+		SchedulerIntervalMinutes: 60,
+	}
+}
+
+func (q *questsConfig) loadFromEnv() {
+	loadEnvUint("KAD_QUESTS_DAILY_COUNT", &q.DailyCount)
+	loadEnvUint("KAD_QUESTS_WEEKLY_COUNT", &q.WeeklyCount)
+	loadEnvUint("KAD_QUESTS_SCHEDULER_INTERVAL", &q.SchedulerIntervalMinutes)
+}
+
 type config struct {
 	Listen   listenConfig `yaml:"listen" json:"listen"`
 	DBConfig pgConfig     `yaml:"db" json:"db"`
+	Mail     mailConfig   `yaml:"mail" json:"mail"`
+	Users    usersConfig  `yaml:"users" json:"users"`
+
+	Inventory  inventoryConfig  `yaml:"inventory" json:"inventory"`
+	Encounters encountersConfig `yaml:"encounters" json:"encounters"`
+	Catches    catchesConfig    `yaml:"catches" json:"catches"`
+	Trades     tradesConfig     `yaml:"trades" json:"trades"`
+	Boxes      boxesConfig      `yaml:"boxes" json:"boxes"`
+	Realtime   realtimeConfig   `yaml:"realtime" json:"realtime"`
+	Nicknames  nicknamesConfig  `yaml:"nicknames" json:"nicknames"`
+	Pokedex    pokedexConfig    `yaml:"pokedex" json:"pokedex"`
+
+	Achievements achievementsConfig `yaml:"achievements" json:"achievements"`
+	Quests       questsConfig       `yaml:"quests" json:"quests"`
 }
 
 func (c *config) loadFromEnv() {
 	c.Listen.loadFromEnv()
 	c.DBConfig.loadFromEnv()
+	c.Mail.loadFromEnv()
+	c.Users.loadFromEnv()
+	c.Inventory.loadFromEnv()
+	c.Encounters.loadFromEnv()
+	c.Catches.loadFromEnv()
+	c.Trades.loadFromEnv()
+	c.Boxes.loadFromEnv()
+	c.Realtime.loadFromEnv()
+	c.Nicknames.loadFromEnv()
+	c.Pokedex.loadFromEnv()
+	c.Achievements.loadFromEnv()
+	c.Quests.loadFromEnv()
 }
 
 func defaultConfig() config {
 	return config{
 		Listen:   defaultListenConfig(),
 		DBConfig: defaultPgConfig(),
+		Mail:     defaultMailConfig(),
+		Users:    defaultUsersConfig(),
+
+		Inventory:  defaultInventoryConfig(),
+		Encounters: defaultEncountersConfig(),
+		Catches:    defaultCatchesConfig(),
+		Trades:     defaultTradesConfig(),
+		Boxes:      defaultBoxesConfig(),
+		Realtime:   defaultRealtimeConfig(),
+		Nicknames:  defaultNicknamesConfig(),
+		Pokedex:    defaultPokedexConfig(),
+
+		Achievements: defaultAchievementsConfig(),
+		Quests:       defaultQuestsConfig(),
 	}
 }
 
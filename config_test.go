@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestExampleConfigComplete keeps config.example.yml in step with the
+// config struct: every section must be there and every key must be known.
+func TestExampleConfigComplete(t *testing.T) {
+	f, err := os.Open("config.example.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var c config
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil {
+		t.Fatalf("cannot decode example config: %v", err)
+	}
+
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var sections map[string]interface{}
+	if err := yaml.NewDecoder(f).Decode(&sections); err != nil {
+		t.Fatal(err)
+	}
+
+	typ := reflect.TypeOf(config{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		if _, ok := sections[name]; !ok {
+			t.Errorf("config.example.yml has no %s section", name)
+		}
+	}
+}
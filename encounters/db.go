@@ -0,0 +1,45 @@
+package encounters
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+var (
+	pool *pgxpool.Pool
+
+	attemptsPerEncounter = 3
+	encounterTTL         = 15 * time.Minute
+	maxSpeciesId         = 1025
+
+	ErrorEncounterNotFound = errors.New("encounter not found")
+	ErrorEncounterExpired  = errors.New("encounter expired")
+	ErrorEncounterClosed   = errors.New("encounter is over")
+	ErrorEncounterActive   = errors.New("finish or flee your current encounter first")
+	ErrorNothingToSpawn    = errors.New("no wild pokemon found")
+
+	ErrorInvalidLocationArea = errors.New("location_area may only contain lowercase letters, digits and -")
+)
+
+func SetPool(newPool *pgxpool.Pool) error {
+	if newPool == nil {
+		return errors.New("Cannot assign nil pool")
+	}
+
+	pool = newPool
+
+	return nil
+}
+
+func SetRules(attempts int, ttl time.Duration, maxSpecies int) error {
+	if attempts <= 0 || ttl <= 0 || maxSpecies <= 0 {
+		return errors.New("Encounter rules must be positive")
+	}
+
+	attemptsPerEncounter = attempts
+	encounterTTL = ttl
+	maxSpeciesId = maxSpecies
+
+	return nil
+}
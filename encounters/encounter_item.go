@@ -0,0 +1,111 @@
+package encounters
+
+import (
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusNone      Status = ""
+	StatusSleep     Status = "sleep"
+	StatusFreeze    Status = "freeze"
+	StatusParalysis Status = "paralysis"
+	StatusPoison    Status = "poison"
+	StatusBurn      Status = "burn"
+)
+
+var wildStatuses = []Status{StatusSleep, StatusFreeze, StatusParalysis, StatusPoison, StatusBurn}
+
+const (
+	OutcomeCaught = "caught"
+	OutcomeFled   = "fled"
+)
+
+type Encounter struct {
+	Id           ulid.ULID
+	UserId       ulid.ULID
+	PokemonId    int
+	Level        int
+	HpPercent    int
+	Status       Status
+	AttemptsLeft int
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	ClosedAt     null.Time
+	Outcome      null.String
+}
+
+const (
+	minWildLevel = 2
+	maxWildLevel = 20
+)
+
+// NewEncounter rolls the wild Pokémon's level and condition: usually
+// healthy, now and then already worn down or afflicted by a status.
+func NewEncounter(userId ulid.ULID, pokemonId int) (Encounter, error) {
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	level := minWildLevel + randIntn(maxWildLevel-minWildLevel+1)
+
+	hp := 100
+	if randIntn(4) == 0 {
+		hp = 20 + randIntn(80)
+	}
+
+	status := StatusNone
+	if randIntn(10) == 0 {
+		status = wildStatuses[randIntn(len(wildStatuses))]
+	}
+
+	now := time.Now()
+
+	return Encounter{
+		Id:           id,
+		UserId:       userId,
+		PokemonId:    pokemonId,
+		Level:        level,
+		HpPercent:    hp,
+		Status:       status,
+		AttemptsLeft: attemptsPerEncounter,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(encounterTTL),
+	}, nil
+}
+
+func (e Encounter) IsOpen(now time.Time) bool {
+	return !e.ClosedAt.Valid && e.AttemptsLeft > 0 && now.Before(e.ExpiresAt)
+}
+
+func (e Encounter) check(now time.Time) error {
+	if e.ClosedAt.Valid || e.AttemptsLeft <= 0 {
+		return ErrorEncounterClosed
+	}
+
+	if !now.Before(e.ExpiresAt) {
+		return ErrorEncounterExpired
+	}
+
+	return nil
+}
+
+// UseAttempt spends one throw. The encounter must still be open.
+func UseAttempt(e *Encounter) error {
+	if err := e.check(time.Now()); err != nil {
+		return err
+	}
+
+	e.AttemptsLeft--
+
+	return nil
+}
+
+func CloseEncounter(e *Encounter, outcome string) {
+	e.ClosedAt = null.TimeFrom(time.Now())
+	e.Outcome = null.StringFrom(outcome)
+}
@@ -0,0 +1,37 @@
+package encounters
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (e Encounter) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id           ulid.ULID  `json:"id"`
+		UserId       ulid.ULID  `json:"user_id"`
+		PokemonId    int        `json:"pokemon_id"`
+		Level        int        `json:"level"`
+		HpPercent    int        `json:"hp_percent"`
+		Status       Status     `json:"status,omitempty"`
+		AttemptsLeft int        `json:"attempts_left"`
+		CreatedAt    time.Time  `json:"created_at"`
+		ExpiresAt    time.Time  `json:"expires_at"`
+		ClosedAt     *time.Time `json:"closed_at,omitempty"`
+		Outcome      *string    `json:"outcome,omitempty"`
+	}
+
+	j.Id = e.Id
+	j.UserId = e.UserId
+	j.PokemonId = e.PokemonId
+	j.Level = e.Level
+	j.HpPercent = e.HpPercent
+	j.Status = e.Status
+	j.AttemptsLeft = e.AttemptsLeft
+	j.CreatedAt = e.CreatedAt
+	j.ExpiresAt = e.ExpiresAt
+	j.ClosedAt = e.ClosedAt.Ptr()
+	j.Outcome = e.Outcome.Ptr()
+
+	return json.Marshal(j)
+}
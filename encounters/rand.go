@@ -0,0 +1,27 @@
+package encounters
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngMu sync.Mutex
+)
+
+// SetRandSource replaces the source used to spawn wild Pokémon.
+func SetRandSource(source rand.Source) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	rng = rand.New(source)
+}
+
+func randIntn(n int) int {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	return rng.Intn(n)
+}
@@ -0,0 +1,85 @@
+package encounters
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+func scanEncounter(row pgx.Row) (Encounter, error) {
+	var e Encounter
+	if err := row.Scan(&e.Id, &e.UserId, &e.PokemonId, &e.Level, &e.HpPercent, &e.Status, &e.AttemptsLeft, &e.CreatedAt, &e.ExpiresAt, &e.ClosedAt, &e.Outcome); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Encounter{}, ErrorEncounterNotFound
+		}
+		return Encounter{}, err
+	}
+
+	return e, nil
+}
+
+func findEncounterById(ctx context.Context, tx pgx.Tx, id, userId ulid.ULID) (Encounter, error) {
+	query := `SELECT id, user_id, pokemon_id, level, hp_percent, status, attempts_left, created_at, expires_at, closed_at, outcome
+				FROM encounters
+			 WHERE id = $1 AND user_id = $2`
+
+	return scanEncounter(tx.QueryRow(ctx, query, id, userId))
+}
+
+// FindForUpdate locks the encounter row for the rest of tx, so two throws at
+// the same encounter are serialised.
+func FindForUpdate(ctx context.Context, tx pgx.Tx, id, userId ulid.ULID) (Encounter, error) {
+	query := `SELECT id, user_id, pokemon_id, level, hp_percent, status, attempts_left, created_at, expires_at, closed_at, outcome
+				FROM encounters
+			 WHERE id = $1 AND user_id = $2
+			 FOR UPDATE`
+
+	return scanEncounter(tx.QueryRow(ctx, query, id, userId))
+}
+
+func findOpenEncounters(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Encounter, error) {
+	query := `SELECT id, user_id, pokemon_id, level, hp_percent, status, attempts_left, created_at, expires_at, closed_at, outcome
+				FROM encounters
+			 WHERE user_id = $1 AND closed_at IS NULL AND attempts_left > 0 AND expires_at > now()
+			 ORDER BY id`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	encounters := []Encounter{}
+	for rows.Next() {
+		e, err := scanEncounter(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		encounters = append(encounters, e)
+	}
+
+	return encounters, rows.Err()
+}
+
+func Save(ctx context.Context, tx pgx.Tx, e Encounter) error {
+	query := `INSERT INTO encounters (id, user_id, pokemon_id, level, hp_percent, status, attempts_left, created_at, expires_at, closed_at, outcome)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			  ON CONFLICT (id) DO UPDATE SET
+				attempts_left = EXCLUDED.attempts_left,
+				closed_at = EXCLUDED.closed_at,
+				outcome = EXCLUDED.outcome;`
+
+	_, err := tx.Exec(ctx, query, e.Id, e.UserId, e.PokemonId, e.Level, e.HpPercent, e.Status, e.AttemptsLeft, e.CreatedAt, e.ExpiresAt, e.ClosedAt, e.Outcome)
+
+	return err
+}
+
+// lockUser serialises spawns per user so the one open encounter rule holds
+// under concurrent requests.
+func lockUser(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, "encounters:"+userId.String())
+
+	return err
+}
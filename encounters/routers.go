@@ -0,0 +1,171 @@
+package encounters
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"io"
+	"mda/helper"
+	"mda/pokemon"
+	"net/http"
+)
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Get("/", listEncountersHandler)
+	r.Post("/", spawnEncounterHandler)
+	r.Get("/{id}", getEncounterHandler)
+	r.Post("/{id}/flee", fleeEncounterHandler)
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+func writeEncounterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorEncounterNotFound), errors.Is(err, pokemon.ErrorLocationAreaNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrorEncounterExpired):
+		writeError(w, http.StatusGone, err)
+	case errors.Is(err, ErrorEncounterClosed), errors.Is(err, ErrorEncounterActive):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrorInvalidLocationArea):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func listEncountersHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	encounters, err := listOpenEncounters(ctx, userId)
+	if err != nil {
+		writeEncounterError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(encounters)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func spawnEncounterHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		LocationArea string `json:"location_area"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	encounter, err := spawnEncounter(ctx, userId, j.LocationArea)
+	if err != nil {
+		writeEncounterError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(encounter)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func getEncounterHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	encounter, err := getEncounter(ctx, id, userId)
+	if err != nil {
+		writeEncounterError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(encounter)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func fleeEncounterHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	_, err = fleeEncounter(ctx, id, userId)
+	if err != nil {
+		writeEncounterError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "Got away safely")
+}
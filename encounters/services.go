@@ -0,0 +1,132 @@
+package encounters
+
+import (
+	"context"
+	"github.com/oklog/ulid/v2"
+)
+
+// spawnEncounter rolls a wild Pokémon for the user, unless they already
+// face one. The roll asks PokeAPI, so it happens before the transaction
+// opens and holds neither a connection nor the user's lock.
+func spawnEncounter(ctx context.Context, userId ulid.ULID, area string) (Encounter, error) {
+	var pokemonId int
+	var err error
+	if area != "" {
+		pokemonId, err = pickAreaPokemon(area)
+	} else {
+		pokemonId, err = pickWildPokemon()
+	}
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Encounter{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockUser(ctx, tx, userId)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	open, err := findOpenEncounters(ctx, tx, userId)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	if len(open) > 0 {
+		return Encounter{}, ErrorEncounterActive
+	}
+
+	encounter, err := NewEncounter(userId, pokemonId)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	err = Save(ctx, tx, encounter)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Encounter{}, err
+	}
+
+	return encounter, nil
+}
+
+func listOpenEncounters(ctx context.Context, userId ulid.ULID) ([]Encounter, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	encounters, err := findOpenEncounters(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return encounters, nil
+}
+
+func getEncounter(ctx context.Context, id, userId ulid.ULID) (Encounter, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Encounter{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	encounter, err := findEncounterById(ctx, tx, id, userId)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Encounter{}, err
+	}
+
+	return encounter, nil
+}
+
+// Find looks up one of the user's encounters without locking it, for
+// callers that prepare outside a transaction before FindForUpdate.
+func Find(ctx context.Context, id, userId ulid.ULID) (Encounter, error) {
+	return getEncounter(ctx, id, userId)
+}
+
+func fleeEncounter(ctx context.Context, id, userId ulid.ULID) (Encounter, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Encounter{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	encounter, err := FindForUpdate(ctx, tx, id, userId)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	if encounter.ClosedAt.Valid {
+		return Encounter{}, ErrorEncounterClosed
+	}
+
+	CloseEncounter(&encounter, OutcomeFled)
+
+	err = Save(ctx, tx, encounter)
+	if err != nil {
+		return Encounter{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Encounter{}, err
+	}
+
+	return encounter, nil
+}
@@ -0,0 +1,64 @@
+package encounters
+
+import (
+	"mda/pokemon"
+	"regexp"
+)
+
+const spawnRolls = 20
+
+// locationAreaName matches PokeAPI location area names such as
+// "viridian-forest-area". Anything else never reaches the PokeAPI URL.
+var locationAreaName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// pickWildPokemon draws a species at random and keeps it with a chance of
+// capture_rate/255, so common Pokémon show up often and legendaries (capture
+// rate 3) almost never.
+func pickWildPokemon() (int, error) {
+	for i := 0; i < spawnRolls; i++ {
+		id := randIntn(maxSpeciesId) + 1
+
+		species, err := pokemon.FindSpecies(id)
+		if err != nil {
+			return 0, err
+		}
+
+		if randIntn(255) < species.CaptureRate {
+			return id, nil
+		}
+	}
+
+	return 0, ErrorNothingToSpawn
+}
+
+// pickAreaPokemon draws from the location area's encounter table, weighted
+// by each Pokémon's encounter chance.
+func pickAreaPokemon(area string) (int, error) {
+	if !locationAreaName.MatchString(area) {
+		return 0, ErrorInvalidLocationArea
+	}
+
+	table, err := pokemon.FindLocationAreaEncounters(area)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, e := range table {
+		total += e.Chance
+	}
+
+	if total == 0 {
+		return 0, ErrorNothingToSpawn
+	}
+
+	roll := randIntn(total)
+	for _, e := range table {
+		if roll < e.Chance {
+			return e.PokemonId, nil
+		}
+		roll -= e.Chance
+	}
+
+	return 0, ErrorNothingToSpawn
+}
@@ -3,9 +3,9 @@ module mda
 go 1.17
 
 require (
-	github.com/go-chi/chi v1.5.1
 	github.com/go-chi/chi/v5 v5.0.8
 	github.com/go-chi/jwtauth v1.2.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/jackc/pgx/v5 v5.4.1
 	github.com/oklog/ulid/v2 v2.1.0
 	github.com/rs/zerolog v1.29.1
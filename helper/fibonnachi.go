@@ -3,12 +3,7 @@ package helper
 import (
 	"strconv"
 	"strings"
-	"sync"
-)
-
-var (
-	fibMap = make(map[string]int)
-	mu     sync.Mutex
+	"unicode/utf8"
 )
 
 func CalculateFibonacci(n int) int {
@@ -25,36 +20,40 @@ func CalculateFibonacci(n int) int {
 	return b
 }
 
-func GetNextFibonacciValue(key string) int {
-	mu.Lock()
-	defer mu.Unlock()
+// GenerateNickName appends -fibValue to baseName, replacing the number a
+// previous rename left there. Other hyphens and spaces are part of the name
+// and stay. The base is cut short so the result is at most maxLength
+// characters.
+func GenerateNickName(baseName string, fibValue, maxLength int) string {
+	baseName = strings.TrimSpace(baseName)
 
-	key = strings.TrimSpace(key)
-	if key == "" {
-		return 0
+	if i := strings.LastIndex(baseName, "-"); i >= 0 && isDigits(baseName[i+1:]) {
+		baseName = baseName[:i]
 	}
 
-	val, exists := fibMap[key]
-	if !exists {
-		val = 0
+	suffix := "-" + strconv.Itoa(fibValue)
+	room := maxLength - utf8.RuneCountInString(suffix)
+	if room < 0 {
+		room = 0
 	}
 
-	fibValue := CalculateFibonacci(val)
-	fibMap[key] = val + 1
+	if runes := []rune(baseName); len(runes) > room {
+		baseName = strings.TrimSpace(string(runes[:room]))
+	}
 
-	return fibValue
+	return baseName + suffix
 }
 
-func GenerateNickName(baseName string, fibValue int) string {
-	baseName = strings.TrimSpace(baseName)
-	baseName = strings.ReplaceAll(baseName, " ", "")
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
 
-	if strings.Contains(baseName, "-") {
-		parts := strings.Split(baseName, "-")
-		if len(parts) > 1 {
-			baseName = strings.Join(parts[:len(parts)-1], "-")
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
 		}
 	}
 
-	return baseName + "-" + strconv.Itoa(fibValue)
+	return true
 }
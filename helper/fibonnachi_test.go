@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestGenerateNickName(t *testing.T) {
+	long := strings.Repeat("a", 50)
+
+	cases := []struct {
+		name     string
+		baseName string
+		fibValue int
+		want     string
+	}{
+		{"first rename", "pikachu", 1, "pikachu-1"},
+		{"replaces the previous number", "pikachu-13", 21, "pikachu-21"},
+		{"keeps hyphens in the name", "mr-mime", 2, "mr-mime-2"},
+		{"keeps hyphens before the number", "jean-luc-3", 5, "jean-luc-5"},
+		{"keeps spaces", "Sir Sparks", 8, "Sir Sparks-8"},
+		{"trims char padding", "pikachu   ", 1, "pikachu-1"},
+		{"cuts a full length name", long, 1, strings.Repeat("a", 48) + "-1"},
+		{"cuts for long numbers", long + "-3", 102334155, strings.Repeat("a", 40) + "-102334155"},
+		{"cuts multibyte names by character", strings.Repeat("é", 50), 34, strings.Repeat("é", 47) + "-34"},
+	}
+
+	for _, c := range cases {
+		got := GenerateNickName(c.baseName, c.fibValue, 50)
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+
+		if n := utf8.RuneCountInString(got); n > 50 {
+			t.Errorf("%s: %d characters is over the limit", c.name, n)
+		}
+	}
+}
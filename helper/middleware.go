@@ -1,7 +1,10 @@
 package helper
 
 import (
+	"context"
+	"errors"
 	"github.com/go-chi/jwtauth"
+	"github.com/oklog/ulid/v2"
 	"net/http"
 )
 
@@ -9,6 +12,8 @@ var (
 	tokenAuth *jwtauth.JWTAuth
 	RoleAdmin = "admin"
 	RoleUser  = "user"
+
+	ErrorNotAuthenticated = errors.New("user not authenticated")
 )
 
 func init() {
@@ -23,6 +28,32 @@ func TokenAuth(next http.Handler) http.Handler {
 	return jwtauth.Verifier(tokenAuth)(next)
 }
 
+// StreamTokenAuth is TokenAuth for endpoints browsers reach without custom
+// headers, such as WebSockets: the token may also come as the jwt cookie.
+// It is never read from the query string, which ends up in access logs.
+func StreamTokenAuth(next http.Handler) http.Handler {
+	return jwtauth.Verify(tokenAuth, jwtauth.TokenFromHeader, jwtauth.TokenFromCookie)(next)
+}
+
+// CurrentUserId reads the user_id claim of the token verified by TokenAuth.
+func CurrentUserId(ctx context.Context) (ulid.ULID, error) {
+	_, claims, _ := jwtauth.FromContext(ctx)
+	userId, ok := claims["user_id"].(string)
+	if !ok || userId == "" {
+		return ulid.ULID{}, ErrorNotAuthenticated
+	}
+
+	return ulid.Parse(userId)
+}
+
+// HasRole reports whether the token verified by TokenAuth carries role.
+func HasRole(ctx context.Context, role string) bool {
+	_, claims, _ := jwtauth.FromContext(ctx)
+	userRole, ok := claims["role"].(string)
+
+	return ok && userRole == role
+}
+
 func RoleMiddleware(role string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
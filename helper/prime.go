@@ -14,20 +14,28 @@ const (
 )
 
 var (
-	PrimeNumbers     = []int{2, 3, 5, 7, 11, 13, 17, 19}
-	NonPrimeNumbers  = []int{1, 4, 6, 8, 9, 10, 12, 14, 15, 16, 18, 20}
-	DefaultAttempts  = 0
-	DefaultThreshold = GenerateThreshold() // Set initial threshold
+	PrimeNumbers    = []int{2, 3, 5, 7, 11, 13, 17, 19}
+	NonPrimeNumbers = []int{1, 4, 6, 8, 9, 10, 12, 14, 15, 16, 18, 20}
+
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngMu sync.Mutex
 )
 
 type PrimeError struct {
-	Number int
+	Number   int
+	Attempts int
 }
 
 func (e *PrimeError) Error() string {
+	if e.Attempts > 0 {
+		return fmt.Sprintf("Not a prime number (%d) after %d attempts", e.Number, e.Attempts)
+	}
 	return fmt.Sprintf("Not a prime number (%d)", e.Number)
 }
 
+// PrimeGenerator draws the numbers behind the release gate. It starts from
+// stored state and reports the state to store back, so callers can keep it
+// wherever they like.
 type PrimeGenerator struct {
 	primes            []int
 	nonPrimes         []int
@@ -37,16 +45,30 @@ type PrimeGenerator struct {
 	mu                sync.Mutex
 }
 
-func NewPrimeGenerator() *PrimeGenerator {
+func NewPrimeGenerator(attempts, threshold int) *PrimeGenerator {
 	return &PrimeGenerator{
 		primes:            append([]int(nil), PrimeNumbers...),
 		nonPrimes:         append([]int(nil), NonPrimeNumbers...),
 		usedPrimes:        make(map[int]bool),
-		attempts:          DefaultAttempts,
-		attemptsThreshold: DefaultThreshold,
+		attempts:          attempts,
+		attemptsThreshold: threshold,
 	}
 }
 
+func (pg *PrimeGenerator) Attempts() int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	return pg.attempts
+}
+
+func (pg *PrimeGenerator) Threshold() int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	return pg.attemptsThreshold
+}
+
 func (pg *PrimeGenerator) GetUniquePrime() (int, error) {
 	pg.mu.Lock()
 	defer pg.mu.Unlock()
@@ -62,8 +84,7 @@ func (pg *PrimeGenerator) GetUniquePrime() (int, error) {
 			return 0, err
 		}
 		pg.attempts++
-		DefaultAttempts = pg.attempts
-		return number, &PrimeError{Number: number}
+		return number, &PrimeError{Number: number, Attempts: pg.attempts}
 	}
 
 	if len(pg.primes) == 0 {
@@ -78,7 +99,6 @@ func (pg *PrimeGenerator) GetUniquePrime() (int, error) {
 
 	pg.usedPrimes[number] = true
 	pg.attempts = 0
-	DefaultAttempts = 0
 	pg.attemptsThreshold = GenerateThreshold() // Update threshold after successful prime generation
 
 	return number, nil
@@ -89,8 +109,7 @@ func (pg *PrimeGenerator) getRandomNumberFromSlice(slice []int) (int, error) {
 		return 0, fmt.Errorf("No numbers available")
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	index := rand.Intn(len(slice))
+	index := randIntn(len(slice))
 	number := slice[index]
 
 	slice = append(slice[:index], slice[index+1:]...)
@@ -112,17 +131,23 @@ func IsPrime(n int) error {
 }
 
 func GenerateThreshold() int {
-	rand.Seed(time.Now().UnixNano())
 	return generatePatternedThreshold()
 }
 
+func randIntn(n int) int {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	return rng.Intn(n)
+}
+
 func generatePatternedThreshold() int {
 	var thresholds []int
-	lastValue := rand.Intn(MaxThreshold-MinThreshold+1) + MinThreshold
+	lastValue := randIntn(MaxThreshold-MinThreshold+1) + MinThreshold
 	thresholds = append(thresholds, lastValue)
 
 	for i := 0; i < 5; i++ {
-		step := rand.Intn(15) + 5
+		step := randIntn(15) + 5
 
 		if i%2 == 0 {
 			newValue := lastValue + step
@@ -0,0 +1,47 @@
+package inventory
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	pool *pgxpool.Pool
+
+	dailyAllowance = []Stack{{Item: PokeBall, Quantity: 5}}
+
+	ErrorUnknownItem      = errors.New("unknown item")
+	ErrorInvalidQuantity  = errors.New("quantity must be positive")
+	ErrorNotEnoughItems   = errors.New("not enough items")
+	ErrorAllowanceClaimed = errors.New("daily allowance already claimed")
+)
+
+func SetPool(newPool *pgxpool.Pool) error {
+	if newPool == nil {
+		return errors.New("Cannot assign nil pool")
+	}
+
+	pool = newPool
+
+	return nil
+}
+
+// SetDailyAllowance changes what a user receives when claiming the daily
+// allowance. Items with a zero quantity are dropped.
+func SetDailyAllowance(allowance []Stack) error {
+	var stacks []Stack
+
+	for _, stack := range allowance {
+		if _, err := ParseItem(string(stack.Item)); err != nil {
+			return err
+		}
+
+		if stack.Quantity > 0 {
+			stacks = append(stacks, stack)
+		}
+	}
+
+	dailyAllowance = stacks
+
+	return nil
+}
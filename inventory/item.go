@@ -0,0 +1,81 @@
+package inventory
+
+import (
+	"github.com/oklog/ulid/v2"
+)
+
+type Item string
+
+const (
+	PokeBall    Item = "poke-ball"
+	GreatBall   Item = "great-ball"
+	UltraBall   Item = "ultra-ball"
+	MasterBall  Item = "master-ball"
+	Potion      Item = "potion"
+	SuperPotion Item = "super-potion"
+	HyperPotion Item = "hyper-potion"
+
+	FireStone    Item = "fire-stone"
+	WaterStone   Item = "water-stone"
+	ThunderStone Item = "thunder-stone"
+	LeafStone    Item = "leaf-stone"
+	MoonStone    Item = "moon-stone"
+	SunStone     Item = "sun-stone"
+	ShinyStone   Item = "shiny-stone"
+	DuskStone    Item = "dusk-stone"
+	DawnStone    Item = "dawn-stone"
+	IceStone     Item = "ice-stone"
+)
+
+var knownItems = []Item{
+	PokeBall,
+	GreatBall,
+	UltraBall,
+	MasterBall,
+	Potion,
+	SuperPotion,
+	HyperPotion,
+	FireStone,
+	WaterStone,
+	ThunderStone,
+	LeafStone,
+	MoonStone,
+	SunStone,
+	ShinyStone,
+	DuskStone,
+	DawnStone,
+	IceStone,
+}
+
+type Stack struct {
+	Item     Item `json:"item"`
+	Quantity int  `json:"quantity"`
+}
+
+type Inventory struct {
+	UserId ulid.ULID `json:"user_id"`
+	Items  []Stack   `json:"items"`
+}
+
+func ParseItem(s string) (Item, error) {
+	for _, item := range knownItems {
+		if string(item) == s {
+			return item, nil
+		}
+	}
+
+	return "", ErrorUnknownItem
+}
+
+func NewStack(item string, quantity int) (Stack, error) {
+	parsed, err := ParseItem(item)
+	if err != nil {
+		return Stack{}, err
+	}
+
+	if quantity <= 0 {
+		return Stack{}, ErrorInvalidQuantity
+	}
+
+	return Stack{Item: parsed, Quantity: quantity}, nil
+}
@@ -0,0 +1,86 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func findInventory(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (Inventory, error) {
+	query := `SELECT item, quantity FROM users_items
+				WHERE user_id = $1 AND quantity > 0
+			  ORDER BY item`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return Inventory{}, err
+	}
+	defer rows.Close()
+
+	inv := Inventory{UserId: userId, Items: []Stack{}}
+
+	for rows.Next() {
+		var stack Stack
+		if err := rows.Scan(&stack.Item, &stack.Quantity); err != nil {
+			return Inventory{}, err
+		}
+
+		inv.Items = append(inv.Items, stack)
+	}
+
+	return inv, rows.Err()
+}
+
+// Grant adds items to the user's inventory within tx.
+func Grant(ctx context.Context, tx pgx.Tx, userId ulid.ULID, stack Stack) error {
+	if stack.Quantity <= 0 {
+		return ErrorInvalidQuantity
+	}
+
+	query := `INSERT INTO users_items (user_id, item, quantity)
+				VALUES ($1, $2, $3)
+			  ON CONFLICT (user_id, item) DO UPDATE SET
+				quantity = users_items.quantity + EXCLUDED.quantity;`
+
+	_, err := tx.Exec(ctx, query, userId, stack.Item, stack.Quantity)
+
+	return err
+}
+
+// Consume takes items out of the user's inventory within tx. The check and
+// the decrement are a single statement, so concurrent requests cannot spend
+// the same item twice.
+func Consume(ctx context.Context, tx pgx.Tx, userId ulid.ULID, stack Stack) error {
+	if stack.Quantity <= 0 {
+		return ErrorInvalidQuantity
+	}
+
+	query := `UPDATE users_items SET quantity = quantity - $3
+				WHERE user_id = $1 AND item = $2 AND quantity >= $3`
+
+	tag, err := tx.Exec(ctx, query, userId, stack.Item, stack.Quantity)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: need %d %s", ErrorNotEnoughItems, stack.Quantity, stack.Item)
+	}
+
+	return nil
+}
+
+func claimAllowanceDay(ctx context.Context, tx pgx.Tx, userId ulid.ULID, day time.Time) (bool, error) {
+	query := `INSERT INTO users_items_allowances (user_id, claimed_on)
+				VALUES ($1, $2)
+			  ON CONFLICT (user_id, claimed_on) DO NOTHING`
+
+	tag, err := tx.Exec(ctx, query, userId, day.Format("2006-01-02"))
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"net/http"
+)
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Get("/", getInventoryHandler)
+	r.Post("/daily", claimDailyHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(helper.RoleMiddleware(helper.RoleAdmin))
+		r.Post("/grant", grantHandler)
+	})
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+func writeInventory(w http.ResponseWriter, status int, inv Inventory) {
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(inv)
+	if err != nil {
+		return
+	}
+}
+
+func getInventoryHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	inv, err := getInventory(ctx, userId)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeInventory(w, http.StatusOK, inv)
+}
+
+func claimDailyHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	inv, err := claimDailyAllowance(ctx, userId)
+	if errors.Is(err, ErrorAllowanceClaimed) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeInventory(w, http.StatusOK, inv)
+}
+
+func grantHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		UserId   ulid.ULID `json:"user_id"`
+		Item     string    `json:"item"`
+		Quantity int       `json:"quantity"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	stack, err := NewStack(j.Item, j.Quantity)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	inv, err := grantItems(ctx, j.UserId, stack)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeInventory(w, http.StatusOK, inv)
+}
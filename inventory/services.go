@@ -0,0 +1,98 @@
+package inventory
+
+import (
+	"context"
+	"github.com/oklog/ulid/v2"
+	"mda/audit"
+	"time"
+)
+
+func getInventory(ctx context.Context, userId ulid.ULID) (Inventory, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Inventory{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	inv, err := findInventory(ctx, tx, userId)
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Inventory{}, err
+	}
+
+	return inv, nil
+}
+
+func grantItems(ctx context.Context, userId ulid.ULID, stack Stack) (Inventory, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Inventory{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = Grant(ctx, tx, userId, stack)
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	event, err := audit.NewEvent(ctx, audit.ActionInventoryGrant, audit.TargetUser, userId.String())
+	if err != nil {
+		return Inventory{}, err
+	}
+	event.Metadata["item"] = stack.Item
+	event.Metadata["quantity"] = stack.Quantity
+
+	err = audit.Record(ctx, tx, event)
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	inv, err := findInventory(ctx, tx, userId)
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Inventory{}, err
+	}
+
+	return inv, nil
+}
+
+// claimDailyAllowance hands out the allowance at most once per UTC day.
+func claimDailyAllowance(ctx context.Context, userId ulid.ULID) (Inventory, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Inventory{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	claimed, err := claimAllowanceDay(ctx, tx, userId, time.Now().UTC())
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	if !claimed {
+		return Inventory{}, ErrorAllowanceClaimed
+	}
+
+	for _, stack := range dailyAllowance {
+		if err := Grant(ctx, tx, userId, stack); err != nil {
+			return Inventory{}, err
+		}
+	}
+
+	inv, err := findInventory(ctx, tx, userId)
+	if err != nil {
+		return Inventory{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Inventory{}, err
+	}
+
+	return inv, nil
+}
@@ -0,0 +1,25 @@
+package leaderboard
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	pool *pgxpool.Pool
+
+	ErrorUnknownMetric = errors.New("unknown leaderboard, use unique_species, catches, rarest_catch or battle_wins")
+	ErrorUnknownPeriod = errors.New("unknown period, use daily, weekly or all_time")
+	ErrorUnknownScope  = errors.New("unknown scope, use global or friends")
+	ErrorInvalidLimit  = errors.New("limit must be between 1 and 100")
+)
+
+func SetPool(newPool *pgxpool.Pool) error {
+	if newPool == nil {
+		return errors.New("Cannot assign nil pool")
+	}
+
+	pool = newPool
+
+	return nil
+}
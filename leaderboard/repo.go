@@ -0,0 +1,111 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func addScore(ctx context.Context, tx pgx.Tx, userId ulid.ULID, metric, period string, start time.Time, delta int64, at time.Time) error {
+	query := `INSERT INTO leaderboard_scores (user_id, metric, period, period_start, score, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (metric, period, period_start, user_id) DO UPDATE SET
+				score = leaderboard_scores.score + EXCLUDED.score,
+				updated_at = EXCLUDED.updated_at`
+
+	_, err := tx.Exec(ctx, query, userId, metric, period, start, delta, at)
+
+	return err
+}
+
+// lowerScore keeps the smallest score seen in the period, along with the
+// species that set it. A tie keeps the species that got there first.
+func lowerScore(ctx context.Context, tx pgx.Tx, userId ulid.ULID, metric, period string, start time.Time, score int64, pokemonId int, at time.Time) error {
+	query := `INSERT INTO leaderboard_scores (user_id, metric, period, period_start, score, pokemon_id, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT (metric, period, period_start, user_id) DO UPDATE SET
+				score = EXCLUDED.score,
+				pokemon_id = EXCLUDED.pokemon_id,
+				updated_at = EXCLUDED.updated_at
+			  WHERE EXCLUDED.score < leaderboard_scores.score`
+
+	_, err := tx.Exec(ctx, query, userId, metric, period, start, score, pokemonId, at)
+
+	return err
+}
+
+// markSpecies records that the user caught the species in the period and
+// reports whether that is new.
+func markSpecies(ctx context.Context, tx pgx.Tx, userId ulid.ULID, period string, start time.Time, pokemonId int) (bool, error) {
+	query := `INSERT INTO leaderboard_species (user_id, period, period_start, pokemon_id)
+				VALUES ($1, $2, $3, $4)
+			  ON CONFLICT DO NOTHING`
+
+	tag, err := tx.Exec(ctx, query, userId, period, start, pokemonId)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// rankedScores ranks the trainers on one board, all of them or only the
+// members passed as $4 when it is not null. Ties share a rank and are
+// listed in the order they reached the score.
+func rankedScores(metric string) string {
+	order := "score DESC"
+	if ascending(metric) {
+		order = "score ASC"
+	}
+
+	return `SELECT rank() OVER (ORDER BY s.` + order + `), s.user_id, u.username, s.score, s.pokemon_id
+			  FROM leaderboard_scores s
+			  JOIN users u ON u.id = s.user_id
+			 WHERE s.metric = $1 AND s.period = $2 AND s.period_start = $3
+			   AND ($4::bytea[] IS NULL OR s.user_id = ANY($4))
+			   AND u.deleted_at IS NULL
+			 ORDER BY s.` + order + `, s.updated_at, s.user_id`
+}
+
+func scanStanding(row pgx.Row) (Standing, error) {
+	var s Standing
+	err := row.Scan(&s.Rank, &s.UserId, &s.Username, &s.Score, &s.PokemonId)
+
+	return s, err
+}
+
+func findStandings(ctx context.Context, tx pgx.Tx, metric, period string, start time.Time, members [][]byte, limit int) ([]Standing, error) {
+	rows, err := tx.Query(ctx, rankedScores(metric)+` LIMIT $5`, metric, period, start, members, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	standings := []Standing{}
+	for rows.Next() {
+		s, err := scanStanding(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		standings = append(standings, s)
+	}
+
+	return standings, rows.Err()
+}
+
+func findStanding(ctx context.Context, tx pgx.Tx, metric, period string, start time.Time, members [][]byte, userId ulid.ULID) (*Standing, error) {
+	query := `SELECT * FROM (` + rankedScores(metric) + `) ranked WHERE user_id = $5`
+
+	s, err := scanStanding(tx.QueryRow(ctx, query, metric, period, start, members, userId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,108 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"mda/helper"
+	"net/http"
+	"strconv"
+)
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Get("/", listMetricsHandler)
+	r.Get("/{metric}", getBoardHandler)
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func listMetricsHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"metrics": Metrics,
+		"periods": Periods,
+		"scopes":  {ScopeGlobal, ScopeFriends},
+	})
+}
+
+func getBoardHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	q := req.URL.Query()
+
+	period := q.Get("period")
+	if period == "" {
+		period = PeriodAllTime
+	}
+
+	scope := q.Get("scope")
+	if scope == "" {
+		scope = ScopeGlobal
+	}
+
+	limit := defaultLimit
+	if s := q.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", s))
+			return
+		}
+		limit = l
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	board, err := getBoard(ctx, userId, chi.URLParam(req, "metric"), period, scope, limit)
+	if errors.Is(err, ErrorUnknownMetric) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrorUnknownPeriod) || errors.Is(err, ErrorUnknownScope) || errors.Is(err, ErrorInvalidLimit) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, board)
+}
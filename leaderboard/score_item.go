@@ -0,0 +1,100 @@
+package leaderboard
+
+import (
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+const (
+	MetricUniqueSpecies = "unique_species"
+	MetricCatches       = "catches"
+	MetricRarestCatch   = "rarest_catch"
+	MetricBattleWins    = "battle_wins"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodAllTime = "all_time"
+)
+
+const (
+	ScopeGlobal  = "global"
+	ScopeFriends = "friends"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+var (
+	Metrics = []string{MetricUniqueSpecies, MetricCatches, MetricRarestCatch, MetricBattleWins}
+	Periods = []string{PeriodDaily, PeriodWeekly, PeriodAllTime}
+)
+
+// allTimeStart is the period start every all-time score is filed under.
+var allTimeStart = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Standing is one trainer's place on a board. For rarest_catch the score
+// is the capture rate of the rarest species caught, so lower ranks higher,
+// and PokemonId names that species.
+type Standing struct {
+	Rank      int       `json:"rank"`
+	UserId    ulid.ULID `json:"user_id"`
+	Username  string    `json:"username"`
+	Score     int64     `json:"score"`
+	PokemonId *int      `json:"pokemon_id,omitempty"`
+}
+
+type Board struct {
+	Metric      string     `json:"metric"`
+	Period      string     `json:"period"`
+	Scope       string     `json:"scope"`
+	PeriodStart time.Time  `json:"period_start"`
+	Standings   []Standing `json:"standings"`
+	Me          *Standing  `json:"me,omitempty"`
+}
+
+func validMetric(metric string) bool {
+	for _, m := range Metrics {
+		if m == metric {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validPeriod(period string) bool {
+	for _, p := range Periods {
+		if p == period {
+			return true
+		}
+	}
+
+	return false
+}
+
+// periodStart is the UTC day, or the Monday of the ISO week, that t falls
+// in. Scores are kept per period start, so a new day or week starts from
+// an empty board.
+func periodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case PeriodDaily:
+		return day
+	case PeriodWeekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return allTimeStart
+	}
+}
+
+// ascending reports whether a lower score ranks higher on the metric.
+func ascending(metric string) bool {
+	return metric == MetricRarestCatch
+}
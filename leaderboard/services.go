@@ -0,0 +1,135 @@
+package leaderboard
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/battles"
+	"mda/pokemon"
+	"mda/users"
+	"mda/userspokemon"
+	"time"
+)
+
+// OnPokemonEvent keeps the catch boards up to date. Subscribe it with
+// userspokemon.Subscribe; only catches count, trades and releases leave
+// the boards as they are.
+func OnPokemonEvent(ctx context.Context, tx pgx.Tx, event userspokemon.HistoryEvent) error {
+	if event.Kind != userspokemon.HistoryCatch {
+		return nil
+	}
+
+	species, err := pokemon.FindSpecies(event.PokemonId)
+	if err != nil {
+		return err
+	}
+
+	for _, period := range Periods {
+		start := periodStart(period, event.CreatedAt)
+
+		err := addScore(ctx, tx, event.OwnerId, MetricCatches, period, start, 1, event.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		isNew, err := markSpecies(ctx, tx, event.OwnerId, period, start, event.PokemonId)
+		if err != nil {
+			return err
+		}
+
+		if isNew {
+			err = addScore(ctx, tx, event.OwnerId, MetricUniqueSpecies, period, start, 1, event.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = lowerScore(ctx, tx, event.OwnerId, MetricRarestCatch, period, start, int64(species.CaptureRate), event.PokemonId, event.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// OnBattle credits the winner of a battle. Subscribe it with
+// battles.Subscribe.
+func OnBattle(ctx context.Context, tx pgx.Tx, battle battles.Battle) error {
+	if battle.WinnerId == nil {
+		return nil
+	}
+
+	for _, period := range Periods {
+		start := periodStart(period, battle.CreatedAt)
+
+		err := addScore(ctx, tx, *battle.WinnerId, MetricBattleWins, period, start, 1, battle.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getBoard ranks everyone, or with ScopeFriends only the user and their
+// friends.
+func getBoard(ctx context.Context, userId ulid.ULID, metric, period, scope string, limit int) (Board, error) {
+	if !validMetric(metric) {
+		return Board{}, ErrorUnknownMetric
+	}
+
+	if !validPeriod(period) {
+		return Board{}, ErrorUnknownPeriod
+	}
+
+	if scope != ScopeGlobal && scope != ScopeFriends {
+		return Board{}, ErrorUnknownScope
+	}
+
+	if limit < 1 || limit > maxLimit {
+		return Board{}, ErrorInvalidLimit
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Board{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var members [][]byte
+	if scope == ScopeFriends {
+		friendIds, err := users.FriendIds(ctx, tx, userId)
+		if err != nil {
+			return Board{}, err
+		}
+
+		members = [][]byte{userId.Bytes()}
+		for _, id := range friendIds {
+			members = append(members, id.Bytes())
+		}
+	}
+
+	board := Board{
+		Metric:      metric,
+		Period:      period,
+		Scope:       scope,
+		PeriodStart: periodStart(period, time.Now()),
+	}
+
+	board.Standings, err = findStandings(ctx, tx, metric, period, board.PeriodStart, members, limit)
+	if err != nil {
+		return Board{}, err
+	}
+
+	board.Me, err = findStanding(ctx, tx, metric, period, board.PeriodStart, members, userId)
+	if err != nil {
+		return Board{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Board{}, err
+	}
+
+	return board, nil
+}
@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrorNoRecipient = errors.New("mail has no recipient")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func validate(msg Message) error {
+	if msg.To == "" {
+		return ErrorNoRecipient
+	}
+
+	return nil
+}
@@ -4,13 +4,23 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"mda/achievements"
+	"mda/audit"
+	"mda/battles"
+	"mda/encounters"
+	"mda/inventory"
+	"mda/leaderboard"
 	"mda/pokemon"
+	"mda/quests"
+	"mda/realtime"
 	"mda/users"
 	"mda/userspokemon"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
@@ -41,8 +51,51 @@ func main() {
 		log.Error().Err(err).Msg("unable to connect to database")
 	}
 
+	audit.SetPool(pool)
+	pokemon.SetPool(pool)
 	users.SetPool(pool)
+	users.SetMailer(cfg.Mail.Mailer())
+	users.SetResetTokenTTL(time.Duration(cfg.Mail.ResetTokenTTLMinutes) * time.Minute)
+	inventory.SetPool(pool)
+	inventory.SetDailyAllowance(cfg.Inventory.DailyAllowance())
+	encounters.SetPool(pool)
+	encounters.SetRules(
+		int(cfg.Encounters.Attempts),
+		time.Duration(cfg.Encounters.TTLMinutes)*time.Minute,
+		int(cfg.Encounters.MaxSpeciesId),
+	)
 	userspokemon.SetPool(pool)
+	userspokemon.SetTradeTTL(time.Duration(cfg.Trades.TTLHours) * time.Hour)
+	userspokemon.SetDefaultBoxCapacity(int(cfg.Boxes.DefaultCapacity))
+	if err := userspokemon.SetOdds(int(cfg.Catches.ShinyOdds), int(cfg.Catches.HiddenAbilityOdds)); err != nil {
+		log.Error().Err(err).Msg("invalid catch odds, use defaults")
+	}
+	userspokemon.SetNicknameFilter(userspokemon.BlocklistFilter(cfg.Nicknames.BlockedWords))
+	battles.SetPool(pool)
+	leaderboard.SetPool(pool)
+	userspokemon.Subscribe(leaderboard.OnPokemonEvent)
+	battles.Subscribe(leaderboard.OnBattle)
+	achievements.SetPool(pool)
+	if cfg.Achievements.RulesFile != "" {
+		err := achievements.LoadRules(cfg.Achievements.RulesFile)
+		if err != nil {
+			log.Error().Str("file", cfg.Achievements.RulesFile).Err(err).Msg("cannot load achievement rules, use built-in rules")
+		}
+	}
+	userspokemon.Subscribe(func(ctx context.Context, tx pgx.Tx, event userspokemon.HistoryEvent) error {
+		_, err := achievements.Evaluate(ctx, tx, event.OwnerId, event.Kind)
+		return err
+	})
+	quests.SetPool(pool)
+	if err := quests.SetCounts(int(cfg.Quests.DailyCount), int(cfg.Quests.WeeklyCount)); err != nil {
+		log.Error().Err(err).Msg("invalid quest counts, use defaults")
+	}
+	userspokemon.Subscribe(quests.OnPokemonEvent)
+	realtime.SetLimits(
+		int(cfg.Realtime.HistorySize),
+		int(cfg.Realtime.SendQueue),
+		time.Duration(cfg.Realtime.PingIntervalSeconds)*time.Second,
+	)
 
 	adminUsername := "admin"
 	adminPassword := "secret"
@@ -58,13 +111,34 @@ func main() {
 		log.Printf("Admin user created: %v", adminUser)
 	}
 
+	pokemon.StartCatalogueSync(ctx, time.Duration(cfg.Pokedex.SyncIntervalHours)*time.Hour)
+
+	quests.StartScheduler(ctx, time.Duration(cfg.Quests.SchedulerIntervalMinutes)*time.Minute)
+
+	users.StartPurgeJob(
+		ctx,
+		time.Duration(cfg.Users.PurgeAfterDays)*24*time.Hour,
+		time.Duration(cfg.Users.PurgeIntervalMinutes)*time.Minute,
+	)
+
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(audit.Middleware)
 	r.Use(middleware.Logger)
 
 	r.Mount("/pokemon", pokemon.Router())
 	r.Mount("/users", users.Router())
 	r.Mount("/users-pokemon", userspokemon.Router())
+	r.Mount("/trades", userspokemon.TradeRouter())
+	r.Mount("/encounters", encounters.Router())
+	r.Mount("/inventory", inventory.Router())
+	r.Mount("/battles", battles.Router())
+	r.Mount("/leaderboards", leaderboard.Router())
+	r.Mount("/achievements", achievements.Router())
+	r.Mount("/quests", quests.Router())
+	r.Mount("/ws", realtime.Router())
+	r.Mount("/audit", audit.Router())
 
 	log.Info().Msg("Starting up server...")
 
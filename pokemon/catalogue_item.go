@@ -0,0 +1,121 @@
+package pokemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const (
+	PokeAPIGenerationURL = "https://pokeapi.co/api/v2/generation/"
+	PokeAPITypeURL       = "https://pokeapi.co/api/v2/type/"
+)
+
+// CatalogueEntry is one species in the locally stored species catalogue.
+type CatalogueEntry struct {
+	Id         int      `json:"id"`
+	Name       string   `json:"name"`
+	Generation string   `json:"generation"`
+	Types      []string `json:"types"`
+}
+
+type resourceList struct {
+	Results []namedResource `json:"results"`
+}
+
+func getJSON(url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from pokeapi: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// fetchCatalogue builds the whole catalogue from the generation and type
+// endpoints, a few dozen requests instead of two per species.
+func fetchCatalogue() ([]CatalogueEntry, error) {
+	entries := make(map[int]*CatalogueEntry)
+
+	var generations resourceList
+	if err := getJSON(PokeAPIGenerationURL+"?limit=100", &generations); err != nil {
+		return nil, err
+	}
+
+	for _, g := range generations.Results {
+		var j struct {
+			Name           string          `json:"name"`
+			PokemonSpecies []namedResource `json:"pokemon_species"`
+		}
+
+		if err := getJSON(g.URL, &j); err != nil {
+			return nil, err
+		}
+
+		for _, s := range j.PokemonSpecies {
+			id := extractSpeciesId(s.URL)
+			if id == 0 {
+				continue
+			}
+
+			entries[id] = &CatalogueEntry{Id: id, Name: s.Name, Generation: j.Name, Types: []string{}}
+		}
+	}
+
+	var types resourceList
+	if err := getJSON(PokeAPITypeURL+"?limit=100", &types); err != nil {
+		return nil, err
+	}
+
+	slots := make(map[int]map[int]string)
+
+	for _, t := range types.Results {
+		var j struct {
+			Pokemon []struct {
+				Slot    int           `json:"slot"`
+				Pokemon namedResource `json:"pokemon"`
+			} `json:"pokemon"`
+		}
+
+		if err := getJSON(t.URL, &j); err != nil {
+			return nil, err
+		}
+
+		// Alternate forms have ids of their own above 10000; only the
+		// default form shares its id with the species.
+		for _, p := range j.Pokemon {
+			id := extractId(p.Pokemon.URL)
+			if _, ok := entries[id]; !ok {
+				continue
+			}
+
+			if slots[id] == nil {
+				slots[id] = make(map[int]string)
+			}
+			slots[id][p.Slot] = t.Name
+		}
+	}
+
+	catalogue := make([]CatalogueEntry, 0, len(entries))
+	for id, entry := range entries {
+		for slot := 1; slot <= len(slots[id]); slot++ {
+			if name, ok := slots[id][slot]; ok {
+				entry.Types = append(entry.Types, name)
+			}
+		}
+
+		catalogue = append(catalogue, *entry)
+	}
+
+	sort.Slice(catalogue, func(i, k int) bool {
+		return catalogue[i].Id < catalogue[k].Id
+	})
+
+	return catalogue, nil
+}
@@ -0,0 +1,21 @@
+package pokemon
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func saveCatalogueEntry(ctx context.Context, tx pgx.Tx, entry CatalogueEntry, syncedAt time.Time) error {
+	query := `INSERT INTO pokemon_species (id, name, generation, types, synced_at)
+				VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				generation = EXCLUDED.generation,
+				types = EXCLUDED.types,
+				synced_at = EXCLUDED.synced_at`
+
+	_, err := tx.Exec(ctx, query, entry.Id, entry.Name, entry.Generation, entry.Types, syncedAt)
+
+	return err
+}
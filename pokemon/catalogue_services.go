@@ -0,0 +1,66 @@
+package pokemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SyncCatalogue refreshes the pokemon_species table from PokeAPI in one
+// transaction, so readers never see a half written catalogue.
+func SyncCatalogue(ctx context.Context) (int, error) {
+	catalogue, err := fetchCatalogue()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	syncedAt := time.Now()
+	for _, entry := range catalogue {
+		if err := saveCatalogueEntry(ctx, tx, entry, syncedAt); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(catalogue), nil
+}
+
+// StartCatalogueSync keeps the species catalogue up to date. It runs once
+// immediately and then on every interval until ctx is cancelled. A zero
+// interval disables the job.
+func StartCatalogueSync(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Info().Msg("species catalogue sync disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			count, err := SyncCatalogue(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("cannot sync species catalogue")
+			} else {
+				log.Info().Int("count", count).Msg("synced species catalogue")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
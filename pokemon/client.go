@@ -0,0 +1,13 @@
+package pokemon
+
+import (
+	"net/http"
+	"time"
+)
+
+// requestTimeout bounds every PokeAPI call, so a slow response cannot hold
+// up a request, or a transaction a caller forgot to keep it out of, for
+// long.
+const requestTimeout = 10 * time.Second
+
+var client = &http.Client{Timeout: requestTimeout}
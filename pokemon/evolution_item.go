@@ -0,0 +1,206 @@
+package pokemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	TriggerLevelUp = "level-up"
+	TriggerUseItem = "use-item"
+	TriggerTrade   = "trade"
+)
+
+// Evolution is one way a species can evolve. A species with several ways
+// to reach the same form, or several forms to reach, has one entry each.
+type Evolution struct {
+	SpeciesId    int    `json:"species_id"`
+	Name         string `json:"name"`
+	Trigger      string `json:"trigger"`
+	MinLevel     int    `json:"min_level,omitempty"`
+	MinHappiness int    `json:"min_happiness,omitempty"`
+	Item         string `json:"item,omitempty"`
+
+	// Supported is false when the evolution depends on conditions this
+	// game does not model, such as time of day or a known move.
+	Supported bool `json:"supported"`
+}
+
+type chainLink struct {
+	Species          namedResource                `json:"species"`
+	EvolutionDetails []map[string]json.RawMessage `json:"evolution_details"`
+	EvolvesTo        []chainLink                  `json:"evolves_to"`
+}
+
+// evolutionKeys are the evolution detail fields understood here; any other
+// field that is set makes the evolution unsupported.
+var evolutionKeys = map[string]bool{
+	"trigger":       true,
+	"min_level":     true,
+	"min_happiness": true,
+	"item":          true,
+}
+
+var (
+	chainCache   = make(map[string]*chainLink)
+	chainCacheMu sync.RWMutex
+)
+
+// FindEvolutions lists what a species can evolve into next.
+func FindEvolutions(speciesId int) ([]Evolution, error) {
+	species, err := FindSpecies(speciesId)
+	if err != nil {
+		return nil, err
+	}
+
+	if species.EvolutionChainURL == "" {
+		return []Evolution{}, nil
+	}
+
+	chain, err := findChain(species.EvolutionChainURL)
+	if err != nil {
+		return nil, err
+	}
+
+	link := chain.find(species.Name)
+	if link == nil {
+		return []Evolution{}, nil
+	}
+
+	evolutions := []Evolution{}
+	for _, next := range link.EvolvesTo {
+		id := extractSpeciesId(next.Species.URL)
+
+		for _, details := range next.EvolutionDetails {
+			evolution, err := parseEvolution(details)
+			if err != nil {
+				return nil, err
+			}
+
+			evolution.SpeciesId = id
+			evolution.Name = next.Species.Name
+			evolutions = append(evolutions, evolution)
+		}
+	}
+
+	return evolutions, nil
+}
+
+func (l *chainLink) find(name string) *chainLink {
+	if l.Species.Name == name {
+		return l
+	}
+
+	for i := range l.EvolvesTo {
+		if found := l.EvolvesTo[i].find(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+func parseEvolution(details map[string]json.RawMessage) (Evolution, error) {
+	var evolution Evolution
+	var trigger, item *namedResource
+	var minLevel, minHappiness *int
+
+	fields := map[string]interface{}{
+		"trigger":       &trigger,
+		"item":          &item,
+		"min_level":     &minLevel,
+		"min_happiness": &minHappiness,
+	}
+
+	for key, target := range fields {
+		raw, ok := details[key]
+		if !ok {
+			continue
+		}
+
+		if err := json.Unmarshal(raw, target); err != nil {
+			return Evolution{}, err
+		}
+	}
+
+	if trigger != nil {
+		evolution.Trigger = trigger.Name
+	}
+
+	if item != nil {
+		evolution.Item = item.Name
+	}
+
+	if minLevel != nil {
+		evolution.MinLevel = *minLevel
+	}
+
+	if minHappiness != nil {
+		evolution.MinHappiness = *minHappiness
+	}
+
+	evolution.Supported = evolution.Trigger == TriggerLevelUp || evolution.Trigger == TriggerUseItem
+	for key, raw := range details {
+		if !evolutionKeys[key] && isSet(raw) {
+			evolution.Supported = false
+		}
+	}
+
+	return evolution, nil
+}
+
+// isSet reports whether a PokeAPI evolution detail field carries a
+// condition, since unused fields come back as null, false or "".
+func isSet(raw json.RawMessage) bool {
+	switch string(raw) {
+	case "", "null", "false", `""`:
+		return false
+	}
+
+	return true
+}
+
+func findChain(url string) (*chainLink, error) {
+	chainCacheMu.RLock()
+	chain, ok := chainCache[url]
+	chainCacheMu.RUnlock()
+
+	if ok {
+		return chain, nil
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from pokeapi: %s", resp.Status)
+	}
+
+	var j struct {
+		Chain chainLink `json:"chain"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+		return nil, err
+	}
+
+	chainCacheMu.Lock()
+	chainCache[url] = &j.Chain
+	chainCacheMu.Unlock()
+
+	return &j.Chain, nil
+}
+
+func extractSpeciesId(url string) int {
+	var id int
+	_, err := fmt.Sscanf(url, PokeAPISpeciesURL+"%d/", &id)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,84 @@
+package pokemon
+
+import (
+	"errors"
+)
+
+const (
+	GrowthFast             = "fast"
+	GrowthMedium           = "medium"
+	GrowthMediumSlow       = "medium-slow"
+	GrowthSlow             = "slow"
+	GrowthSlowThenVeryFast = "slow-then-very-fast"
+	GrowthFastThenVerySlow = "fast-then-very-slow"
+)
+
+var ErrorUnknownGrowthRate = errors.New("unknown growth rate")
+
+// ExperienceForLevel is the total experience a Pokémon on the given growth
+// curve needs to reach a level, as defined by the main series games.
+func ExperienceForLevel(growthRate string, level int) (int, error) {
+	if level <= 1 {
+		return 0, nil
+	}
+
+	if level > MaxLevel {
+		level = MaxLevel
+	}
+
+	n := level
+	cube := n * n * n
+
+	switch growthRate {
+	case GrowthFast:
+		return 4 * cube / 5, nil
+	case GrowthMedium:
+		return cube, nil
+	case GrowthMediumSlow:
+		return 6*cube/5 - 15*n*n + 100*n - 140, nil
+	case GrowthSlow:
+		return 5 * cube / 4, nil
+	case GrowthSlowThenVeryFast:
+		switch {
+		case n < 50:
+			return cube * (100 - n) / 50, nil
+		case n < 68:
+			return cube * (150 - n) / 100, nil
+		case n < 98:
+			return cube * ((1911 - 10*n) / 3) / 500, nil
+		default:
+			return cube * (160 - n) / 100, nil
+		}
+	case GrowthFastThenVerySlow:
+		switch {
+		case n < 15:
+			return cube * ((n+1)/3 + 24) / 50, nil
+		case n < 36:
+			return cube * (n + 14) / 50, nil
+		default:
+			return cube * (n/2 + 32) / 50, nil
+		}
+	}
+
+	return 0, ErrorUnknownGrowthRate
+}
+
+// LevelForExperience is the highest level the given experience reaches.
+func LevelForExperience(growthRate string, experience int) (int, error) {
+	level := 1
+
+	for level < MaxLevel {
+		next, err := ExperienceForLevel(growthRate, level+1)
+		if err != nil {
+			return 0, err
+		}
+
+		if experience < next {
+			break
+		}
+
+		level++
+	}
+
+	return level, nil
+}
@@ -0,0 +1,71 @@
+package pokemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const PokeAPILocationAreaURL = "https://pokeapi.co/api/v2/location-area/"
+
+var ErrorLocationAreaNotFound = errors.New("location area not found")
+
+type AreaEncounter struct {
+	PokemonId int `json:"pokemon_id"`
+	Chance    int `json:"chance"`
+}
+
+// FindLocationAreaEncounters lists the Pokémon found in a location area with
+// the best encounter chance any game version gives them.
+func FindLocationAreaEncounters(name string) ([]AreaEncounter, error) {
+	resp, err := client.Get(fmt.Sprintf("%s%s/", PokeAPILocationAreaURL, url.PathEscape(name)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrorLocationAreaNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from pokeapi: %s", resp.Status)
+	}
+
+	var j struct {
+		PokemonEncounters []struct {
+			Pokemon        namedResource `json:"pokemon"`
+			VersionDetails []struct {
+				MaxChance int `json:"max_chance"`
+			} `json:"version_details"`
+		} `json:"pokemon_encounters"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+		return nil, err
+	}
+
+	var encounters []AreaEncounter
+
+	for _, e := range j.PokemonEncounters {
+		id := extractId(e.Pokemon.URL)
+		if id == 0 {
+			continue
+		}
+
+		chance := 0
+		for _, v := range e.VersionDetails {
+			if v.MaxChance > chance {
+				chance = v.MaxChance
+			}
+		}
+
+		if chance > 0 {
+			encounters = append(encounters, AreaEncounter{PokemonId: id, Chance: chance})
+		}
+	}
+
+	return encounters, nil
+}
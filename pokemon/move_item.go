@@ -0,0 +1,140 @@
+package pokemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const PokeAPIMoveURL = "https://pokeapi.co/api/v2/move/"
+
+const (
+	DamagePhysical = "physical"
+	DamageSpecial  = "special"
+	DamageStatus   = "status"
+)
+
+const MovesetSize = 4
+
+var ErrorMoveNotFound = errors.New("move not found")
+
+type Move struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Power       int    `json:"power"`
+	Accuracy    int    `json:"accuracy"`
+	PP          int    `json:"pp"`
+	Priority    int    `json:"priority"`
+	DamageClass string `json:"damage_class"`
+}
+
+// LearnedMove is a move a Pokémon picks up by levelling.
+type LearnedMove struct {
+	Name  string `json:"name"`
+	Level int    `json:"level"`
+}
+
+var (
+	moveCache   = make(map[string]*Move)
+	moveCacheMu sync.RWMutex
+)
+
+func FindMove(name string) (*Move, error) {
+	moveCacheMu.RLock()
+	move, ok := moveCache[name]
+	moveCacheMu.RUnlock()
+
+	if ok {
+		return move, nil
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s%s/", PokeAPIMoveURL, name))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrorMoveNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from pokeapi: %s", resp.Status)
+	}
+
+	var j struct {
+		Id          int           `json:"id"`
+		Name        string        `json:"name"`
+		Type        namedResource `json:"type"`
+		Power       *int          `json:"power"`
+		Accuracy    *int          `json:"accuracy"`
+		PP          int           `json:"pp"`
+		Priority    int           `json:"priority"`
+		DamageClass namedResource `json:"damage_class"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+		return nil, err
+	}
+
+	move = &Move{
+		Id:          j.Id,
+		Name:        j.Name,
+		Type:        j.Type.Name,
+		PP:          j.PP,
+		Priority:    j.Priority,
+		DamageClass: j.DamageClass.Name,
+	}
+
+	// A missing power means the move deals no direct damage; a missing
+	// accuracy means it never misses, which is stored as 0.
+	if j.Power != nil {
+		move.Power = *j.Power
+	}
+
+	if j.Accuracy != nil {
+		move.Accuracy = *j.Accuracy
+	}
+
+	moveCacheMu.Lock()
+	moveCache[name] = move
+	moveCacheMu.Unlock()
+
+	return move, nil
+}
+
+// FindMoveset returns the moves a Pokémon of the given form and level
+// knows: the last four it learned by levelling, as in the games' default
+// movesets.
+func FindMoveset(profile *Profile, level int) ([]Move, error) {
+	known := make([]LearnedMove, 0, len(profile.LevelUpMoves))
+	for _, learned := range profile.LevelUpMoves {
+		if learned.Level <= level {
+			known = append(known, learned)
+		}
+	}
+
+	sort.SliceStable(known, func(i, j int) bool {
+		return known[i].Level < known[j].Level
+	})
+
+	if len(known) > MovesetSize {
+		known = known[len(known)-MovesetSize:]
+	}
+
+	moves := make([]Move, 0, len(known))
+	for _, learned := range known {
+		move, err := FindMove(learned.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		moves = append(moves, *move)
+	}
+
+	return moves, nil
+}
@@ -0,0 +1,56 @@
+package pokemon
+
+import (
+	"errors"
+)
+
+type Nature string
+
+var ErrorUnknownNature = errors.New("unknown nature")
+
+// Natures lists all 25 natures in their canonical order: the nature at
+// 5*i+j raises natureStats[i] and lowers natureStats[j], and the five with
+// i == j are neutral.
+var Natures = []Nature{
+	"hardy", "lonely", "brave", "adamant", "naughty",
+	"bold", "docile", "relaxed", "impish", "lax",
+	"timid", "hasty", "serious", "jolly", "naive",
+	"modest", "mild", "quiet", "bashful", "rash",
+	"calm", "gentle", "sassy", "careful", "quirky",
+}
+
+var natureStats = [5]int{StatAttack, StatDefense, StatSpeed, StatSpecialAttack, StatSpecialDefense}
+
+func ParseNature(s string) (Nature, error) {
+	for _, n := range Natures {
+		if string(n) == s {
+			return n, nil
+		}
+	}
+
+	return "", ErrorUnknownNature
+}
+
+// modifier returns the nature's effect on a stat in tenths: 11 when it is
+// raised, 9 when it is lowered, 10 otherwise.
+func (n Nature) modifier(stat int) int {
+	for idx, candidate := range Natures {
+		if candidate != n {
+			continue
+		}
+
+		raised, lowered := natureStats[idx/5], natureStats[idx%5]
+		if raised == lowered {
+			return 10
+		}
+
+		switch stat {
+		case raised:
+			return 11
+		case lowered:
+			return 9
+		}
+	}
+
+	return 10
+}
@@ -3,7 +3,6 @@ package pokemon
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
 )
 
 const PokeAPIURL = "https://pokeapi.co/api/v2/pokemon/"
@@ -64,7 +63,7 @@ func listPokemon(limit, offset int) (*PokemonListResponse, error) {
 		offset = defaultOffset
 	}
 
-	resp, err := http.Get(fmt.Sprintf("%s?limit=%d&offset=%d", PokeAPIURL, limit, offset))
+	resp, err := client.Get(fmt.Sprintf("%s?limit=%d&offset=%d", PokeAPIURL, limit, offset))
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +82,7 @@ func listPokemon(limit, offset int) (*PokemonListResponse, error) {
 }
 
 func findPokemonById(id int) (*Pokemon, error) {
-	resp, err := http.Get(fmt.Sprintf("%s%d/", PokeAPIURL, id))
+	resp, err := client.Get(fmt.Sprintf("%s%d/", PokeAPIURL, id))
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +97,7 @@ func findPokemonById(id int) (*Pokemon, error) {
 }
 
 func findPokemonByName(name string) (*Pokemon, error) {
-	resp, err := http.Get(fmt.Sprintf("%s%s/", PokeAPIURL, name))
+	resp, err := client.Get(fmt.Sprintf("%s%s/", PokeAPIURL, name))
 	if err != nil {
 		return nil, err
 	}
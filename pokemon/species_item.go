@@ -0,0 +1,107 @@
+package pokemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const PokeAPISpeciesURL = "https://pokeapi.co/api/v2/pokemon-species/"
+
+var (
+	ErrorPokemonNotFound = errors.New("pokemon not found")
+
+	speciesCache   = make(map[int]*Species)
+	speciesCacheMu sync.RWMutex
+)
+
+type namedResource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Species holds the species level data the game mechanics rely on. Species
+// never change, so they are cached for the lifetime of the process.
+type Species struct {
+	Id                int    `json:"id"`
+	Name              string `json:"name"`
+	CaptureRate       int    `json:"capture_rate"`
+	BaseHappiness     int    `json:"base_happiness"`
+	GenderRate        int    `json:"gender_rate"`
+	GrowthRate        string `json:"growth_rate"`
+	Generation        string `json:"generation"`
+	IsLegendary       bool   `json:"is_legendary"`
+	IsMythical        bool   `json:"is_mythical"`
+	EvolutionChainURL string `json:"evolution_chain_url"`
+}
+
+func FindSpecies(id int) (*Species, error) {
+	speciesCacheMu.RLock()
+	species, ok := speciesCache[id]
+	speciesCacheMu.RUnlock()
+
+	if ok {
+		return species, nil
+	}
+
+	species, err := fetchSpecies(id)
+	if err != nil {
+		return nil, err
+	}
+
+	speciesCacheMu.Lock()
+	speciesCache[id] = species
+	speciesCacheMu.Unlock()
+
+	return species, nil
+}
+
+func fetchSpecies(id int) (*Species, error) {
+	resp, err := client.Get(fmt.Sprintf("%s%d/", PokeAPISpeciesURL, id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrorPokemonNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from pokeapi: %s", resp.Status)
+	}
+
+	var j struct {
+		Id             int           `json:"id"`
+		Name           string        `json:"name"`
+		CaptureRate    int           `json:"capture_rate"`
+		BaseHappiness  int           `json:"base_happiness"`
+		GenderRate     int           `json:"gender_rate"`
+		GrowthRate     namedResource `json:"growth_rate"`
+		Generation     namedResource `json:"generation"`
+		IsLegendary    bool          `json:"is_legendary"`
+		IsMythical     bool          `json:"is_mythical"`
+		EvolutionChain struct {
+			URL string `json:"url"`
+		} `json:"evolution_chain"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+		return nil, err
+	}
+
+	return &Species{
+		Id:                j.Id,
+		Name:              j.Name,
+		CaptureRate:       j.CaptureRate,
+		BaseHappiness:     j.BaseHappiness,
+		GenderRate:        j.GenderRate,
+		GrowthRate:        j.GrowthRate.Name,
+		Generation:        j.Generation.Name,
+		IsLegendary:       j.IsLegendary,
+		IsMythical:        j.IsMythical,
+		EvolutionChainURL: j.EvolutionChain.URL,
+	}, nil
+}
@@ -0,0 +1,231 @@
+package pokemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	StatHP = iota
+	StatAttack
+	StatDefense
+	StatSpecialAttack
+	StatSpecialDefense
+	StatSpeed
+)
+
+const (
+	MaxLevel   = 100
+	MaxIV      = 31
+	MaxStatEV  = 252
+	MaxTotalEV = 510
+)
+
+var StatNames = [6]string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
+
+var ErrorInvalidStats = errors.New("stats must have exactly six values")
+
+// Stats holds one value per stat, indexed by the Stat constants.
+type Stats [6]int
+
+func StatsFromSlice(values []int) (Stats, error) {
+	var s Stats
+	if len(values) != len(s) {
+		return Stats{}, ErrorInvalidStats
+	}
+
+	copy(s[:], values)
+
+	return s, nil
+}
+
+func (s Stats) Total() int {
+	total := 0
+	for _, v := range s {
+		total += v
+	}
+
+	return total
+}
+
+func (s Stats) MarshalJSON() ([]byte, error) {
+	j := make(map[string]int, len(s))
+	for i, v := range s {
+		j[StatNames[i]] = v
+	}
+
+	return json.Marshal(j)
+}
+
+func (s *Stats) UnmarshalJSON(data []byte) error {
+	var j map[string]int
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	for i, name := range StatNames {
+		s[i] = j[name]
+	}
+
+	return nil
+}
+
+// Profile is the per-form data from /pokemon/{id} that stat and battle
+// calculations need.
+type Profile struct {
+	Id             int       `json:"id"`
+	Name           string    `json:"name"`
+	BaseExperience int       `json:"base_experience"`
+	BaseStats      Stats     `json:"base_stats"`
+	EffortYield    Stats     `json:"effort_yield"`
+	Types          []string  `json:"types"`
+	Abilities      []Ability `json:"abilities"`
+
+	LevelUpMoves []LearnedMove `json:"level_up_moves"`
+}
+
+// Ability is one of the abilities a form can have. Slots 1 and 2 are the
+// regular abilities, slot 3 the hidden one.
+type Ability struct {
+	Name   string `json:"name"`
+	Slot   int    `json:"slot"`
+	Hidden bool   `json:"hidden"`
+}
+
+var (
+	profileCache   = make(map[int]*Profile)
+	profileCacheMu sync.RWMutex
+)
+
+func FindProfile(id int) (*Profile, error) {
+	profileCacheMu.RLock()
+	profile, ok := profileCache[id]
+	profileCacheMu.RUnlock()
+
+	if ok {
+		return profile, nil
+	}
+
+	profile, err := fetchProfile(id)
+	if err != nil {
+		return nil, err
+	}
+
+	profileCacheMu.Lock()
+	profileCache[id] = profile
+	profileCacheMu.Unlock()
+
+	return profile, nil
+}
+
+func fetchProfile(id int) (*Profile, error) {
+	resp, err := client.Get(fmt.Sprintf("%s%d/", PokeAPIURL, id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrorPokemonNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from pokeapi: %s", resp.Status)
+	}
+
+	var j struct {
+		Id             int    `json:"id"`
+		Name           string `json:"name"`
+		BaseExperience int    `json:"base_experience"`
+		Stats          []struct {
+			BaseStat int           `json:"base_stat"`
+			Effort   int           `json:"effort"`
+			Stat     namedResource `json:"stat"`
+		} `json:"stats"`
+		Types []struct {
+			Slot int           `json:"slot"`
+			Type namedResource `json:"type"`
+		} `json:"types"`
+		Abilities []struct {
+			Ability  namedResource `json:"ability"`
+			IsHidden bool          `json:"is_hidden"`
+			Slot     int           `json:"slot"`
+		} `json:"abilities"`
+		Moves []struct {
+			Move                namedResource `json:"move"`
+			VersionGroupDetails []struct {
+				LevelLearnedAt  int           `json:"level_learned_at"`
+				MoveLearnMethod namedResource `json:"move_learn_method"`
+			} `json:"version_group_details"`
+		} `json:"moves"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{
+		Id:             j.Id,
+		Name:           j.Name,
+		BaseExperience: j.BaseExperience,
+		Types:          make([]string, 0, len(j.Types)),
+		Abilities:      make([]Ability, 0, len(j.Abilities)),
+		LevelUpMoves:   []LearnedMove{},
+	}
+
+	for _, s := range j.Stats {
+		for i, name := range StatNames {
+			if s.Stat.Name == name {
+				profile.BaseStats[i] = s.BaseStat
+				profile.EffortYield[i] = s.Effort
+			}
+		}
+	}
+
+	for _, t := range j.Types {
+		profile.Types = append(profile.Types, t.Type.Name)
+	}
+
+	for _, a := range j.Abilities {
+		profile.Abilities = append(profile.Abilities, Ability{Name: a.Ability.Name, Slot: a.Slot, Hidden: a.IsHidden})
+	}
+
+	// Learn levels differ between games; the most recent version group,
+	// listed last, wins.
+	for _, m := range j.Moves {
+		level := -1
+		for _, d := range m.VersionGroupDetails {
+			if d.MoveLearnMethod.Name == "level-up" {
+				level = d.LevelLearnedAt
+			}
+		}
+
+		if level >= 0 {
+			profile.LevelUpMoves = append(profile.LevelUpMoves, LearnedMove{Name: m.Move.Name, Level: level})
+		}
+	}
+
+	return profile, nil
+}
+
+// CalculateStats applies the main series formulas (Gen III onwards) to turn
+// base stats, IVs, EVs, level and nature into the stats a Pokémon has.
+func CalculateStats(base, ivs, evs Stats, level int, nature Nature) Stats {
+	var stats Stats
+
+	for i := range stats {
+		core := (2*base[i] + ivs[i] + evs[i]/4) * level / 100
+
+		if i == StatHP {
+			stats[i] = core + level + 10
+			continue
+		}
+
+		stats[i] = (core + 5) * nature.modifier(i) / 10
+	}
+
+	return stats
+}
@@ -0,0 +1,44 @@
+package quests
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	pool *pgxpool.Pool
+
+	dailyCount  = 3
+	weeklyCount = 2
+
+	ErrorTemplateIdRequired    = errors.New("quest id is required")
+	ErrorTemplateDuplicate     = errors.New("quest id is used more than once")
+	ErrorTemplateUnknownPeriod = errors.New("quest period must be daily or weekly")
+	ErrorTemplateUnknownEvent  = errors.New("quest event must be one of catch, release, unrelease, rename, trade, level_up or evolve")
+	ErrorTemplateInvalidTarget = errors.New("quest target must be positive")
+	ErrorTemplateInvalidReward = errors.New("quest reward must be an item with a positive quantity, experience, or both")
+	ErrorInvalidCount          = errors.New("quest count cannot be negative")
+)
+
+func SetPool(newPool *pgxpool.Pool) error {
+	if newPool == nil {
+		return errors.New("Cannot assign nil pool")
+	}
+
+	pool = newPool
+
+	return nil
+}
+
+// SetCounts changes how many quests a user is handed each day and each
+// week. Quests already generated for the current period are kept.
+func SetCounts(daily, weekly int) error {
+	if daily < 0 || weekly < 0 {
+		return ErrorInvalidCount
+	}
+
+	dailyCount = daily
+	weeklyCount = weekly
+
+	return nil
+}
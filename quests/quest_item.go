@@ -0,0 +1,53 @@
+package quests
+
+import (
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+// Quest is a template handed to one user for one day or week.
+type Quest struct {
+	Id          ulid.ULID
+	UserId      ulid.ULID
+	TemplateId  string
+	Period      string
+	PeriodStart time.Time
+	Progress    int
+	Target      int
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+}
+
+func NewQuest(userId ulid.ULID, template Template, start time.Time) (Quest, error) {
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return Quest{}, err
+	}
+
+	return Quest{
+		Id:          id,
+		UserId:      userId,
+		TemplateId:  template.Id,
+		Period:      template.Period,
+		PeriodStart: start,
+		Target:      template.Target,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// Advance counts one more matching event and reports whether that
+// completed the quest. Completed quests stay as they are.
+func Advance(quest *Quest, at time.Time) bool {
+	if quest.CompletedAt != nil {
+		return false
+	}
+
+	quest.Progress++
+	if quest.Progress < quest.Target {
+		return false
+	}
+
+	quest.CompletedAt = &at
+
+	return true
+}
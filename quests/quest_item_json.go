@@ -0,0 +1,39 @@
+package quests
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (q Quest) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id          ulid.ULID  `json:"id"`
+		TemplateId  string     `json:"template_id"`
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Period      string     `json:"period"`
+		Progress    int        `json:"progress"`
+		Target      int        `json:"target"`
+		Reward      *Reward    `json:"reward,omitempty"`
+		CompletedAt *time.Time `json:"completed_at"`
+		ExpiresAt   time.Time  `json:"expires_at"`
+	}
+
+	j.Id = q.Id
+	j.TemplateId = q.TemplateId
+	j.Name = q.TemplateId
+	j.Period = q.Period
+	j.Progress = q.Progress
+	j.Target = q.Target
+	j.CompletedAt = q.CompletedAt
+	j.ExpiresAt = periodEnd(q.Period, q.PeriodStart)
+
+	if template, ok := findTemplate(q.TemplateId); ok {
+		j.Name = template.Name
+		j.Description = template.Description
+		j.Reward = &template.Reward
+	}
+
+	return json.Marshal(j)
+}
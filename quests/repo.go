@@ -0,0 +1,108 @@
+package quests
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+// claimGeneration marks the user's quests for the period as generated and
+// reports whether this call was the first to do so. A concurrent claim
+// waits on the primary key until the first one commits or rolls back.
+func claimGeneration(ctx context.Context, tx pgx.Tx, userId ulid.ULID, period string, start time.Time) (bool, error) {
+	query := `INSERT INTO users_quests_generations (user_id, period, period_start, generated_at)
+				VALUES ($1, $2, $3, $4)
+			  ON CONFLICT DO NOTHING`
+
+	tag, err := tx.Exec(ctx, query, userId, period, start, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func saveQuest(ctx context.Context, tx pgx.Tx, quest Quest) error {
+	query := `INSERT INTO users_quests (id, user_id, template_id, period, period_start, progress, target, completed_at, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  ON CONFLICT (id) DO UPDATE SET
+				progress = EXCLUDED.progress,
+				completed_at = EXCLUDED.completed_at`
+
+	_, err := tx.Exec(ctx, query, quest.Id, quest.UserId, quest.TemplateId, quest.Period, quest.PeriodStart,
+		quest.Progress, quest.Target, quest.CompletedAt, quest.CreatedAt)
+
+	return err
+}
+
+// currentQuests selects the user's quests for the day starting at $2 and
+// the week starting at $3.
+const currentQuests = `SELECT id, user_id, template_id, period, period_start, progress, target, completed_at, created_at
+						 FROM users_quests
+						WHERE user_id = $1
+						  AND ((period = 'daily' AND period_start = $2) OR (period = 'weekly' AND period_start = $3))`
+
+func scanQuests(rows pgx.Rows) ([]Quest, error) {
+	defer rows.Close()
+
+	list := make([]Quest, 0)
+	for rows.Next() {
+		var q Quest
+		err := rows.Scan(&q.Id, &q.UserId, &q.TemplateId, &q.Period, &q.PeriodStart, &q.Progress, &q.Target, &q.CompletedAt, &q.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, q)
+	}
+
+	return list, rows.Err()
+}
+
+func findCurrentQuests(ctx context.Context, tx pgx.Tx, userId ulid.ULID, day, week time.Time) ([]Quest, error) {
+	rows, err := tx.Query(ctx, currentQuests+` ORDER BY period, template_id`, userId, day, week)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanQuests(rows)
+}
+
+// findOpenQuestsForUpdate locks the user's unfinished quests so events
+// arriving together cannot both count towards the same total.
+func findOpenQuestsForUpdate(ctx context.Context, tx pgx.Tx, userId ulid.ULID, day, week time.Time) ([]Quest, error) {
+	rows, err := tx.Query(ctx, currentQuests+` AND completed_at IS NULL ORDER BY id FOR UPDATE`, userId, day, week)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanQuests(rows)
+}
+
+// findActiveUserIds pages through users that are neither deleted nor
+// anonymized, in id order after the given id.
+func findActiveUserIds(ctx context.Context, tx pgx.Tx, after ulid.ULID, limit int) ([]ulid.ULID, error) {
+	query := `SELECT id FROM users
+			   WHERE id > $1 AND deleted_at IS NULL AND anonymized_at IS NULL
+			   ORDER BY id
+			   LIMIT $2`
+
+	rows, err := tx.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []ulid.ULID
+	for rows.Next() {
+		var id ulid.ULID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,102 @@
+package quests
+
+import (
+	"context"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const schedulerBatch = 100
+
+// StartScheduler generates every active user's quests for the current day
+// and week in the background, so they are ready before anyone asks.
+// Generation is claimed per user and period, so several replicas, or a
+// restart halfway through a run, hand out each set of quests only once.
+func StartScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Info().Msg("quest scheduler disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			count, err := generateAll(ctx, time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("cannot generate quests")
+			} else if count > 0 {
+				log.Info().Int("count", count).Msg("generated quests")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// generateAll walks the users in batches, one transaction per user, and
+// returns how many of them got new quests.
+func generateAll(ctx context.Context, now time.Time) (int, error) {
+	var after ulid.ULID
+	count := 0
+
+	for {
+		ids, err := findActiveUserBatch(ctx, after)
+		if err != nil {
+			return count, err
+		}
+
+		for _, id := range ids {
+			generated, err := generateFor(ctx, id, now)
+			if err != nil {
+				return count, err
+			}
+
+			if generated {
+				count++
+			}
+		}
+
+		if len(ids) < schedulerBatch {
+			return count, nil
+		}
+
+		after = ids[len(ids)-1]
+	}
+}
+
+func findActiveUserBatch(ctx context.Context, after ulid.ULID) ([]ulid.ULID, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := findActiveUserIds(ctx, tx, after, schedulerBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit(ctx)
+}
+
+func generateFor(ctx context.Context, userId ulid.ULID, now time.Time) (bool, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	generated, err := ensureQuests(ctx, tx, userId, now)
+	if err != nil {
+		return false, err
+	}
+
+	return generated, tx.Commit(ctx)
+}
@@ -0,0 +1,191 @@
+package quests
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/audit"
+	"mda/inventory"
+	"mda/pokemon"
+	"mda/userspokemon"
+	"time"
+)
+
+// ensureQuests hands the user their quests for the day and week that now
+// falls in, unless that already happened in this or another process. It
+// reports whether any quests were generated.
+func ensureQuests(ctx context.Context, tx pgx.Tx, userId ulid.ULID, now time.Time) (bool, error) {
+	generated := false
+
+	for _, period := range Periods {
+		start := periodStart(period, now)
+
+		claimed, err := claimGeneration(ctx, tx, userId, period, start)
+		if err != nil {
+			return false, err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		for _, template := range pickTemplates(userId, period, start, periodCount(period)) {
+			quest, err := NewQuest(userId, template, start)
+			if err != nil {
+				return false, err
+			}
+
+			if err := saveQuest(ctx, tx, quest); err != nil {
+				return false, err
+			}
+		}
+
+		generated = true
+	}
+
+	return generated, nil
+}
+
+// OnPokemonEvent advances the owner's open quests that count the event and
+// pays out the ones it completes. Subscribe it with userspokemon.Subscribe.
+func OnPokemonEvent(ctx context.Context, tx pgx.Tx, event userspokemon.HistoryEvent) error {
+	if _, err := ensureQuests(ctx, tx, event.OwnerId, event.CreatedAt); err != nil {
+		return err
+	}
+
+	open, err := findOpenQuestsForUpdate(ctx, tx, event.OwnerId,
+		periodStart(PeriodDaily, event.CreatedAt), periodStart(PeriodWeekly, event.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	var types []string
+	var completed []Quest
+	for _, quest := range open {
+		template, ok := findTemplate(quest.TemplateId)
+		if !ok || template.Event != event.Kind {
+			continue
+		}
+
+		if template.Type != "" {
+			if types == nil {
+				profile, err := pokemon.FindProfile(event.PokemonId)
+				if err != nil {
+					return err
+				}
+				types = profile.Types
+			}
+
+			if !hasType(types, template.Type) {
+				continue
+			}
+		}
+
+		done := Advance(&quest, event.CreatedAt)
+		if err := saveQuest(ctx, tx, quest); err != nil {
+			return err
+		}
+
+		if done {
+			completed = append(completed, quest)
+		}
+	}
+
+	// Rewards are paid once every quest is saved: experience can level a
+	// Pokémon up, which comes back through this hook for the same user.
+	for _, quest := range completed {
+		template, _ := findTemplate(quest.TemplateId)
+		if err := payReward(ctx, tx, quest, template.Reward, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasType(types []string, want string) bool {
+	for _, t := range types {
+		if t == want {
+			return true
+		}
+	}
+
+	return false
+}
+
+// payReward grants a completed quest's items and experience. Experience
+// goes to the party lead, or to the Pokémon behind the event when the
+// party is empty; with neither around it is forfeited.
+func payReward(ctx context.Context, tx pgx.Tx, quest Quest, reward Reward, event userspokemon.HistoryEvent) error {
+	auditEvent, err := audit.NewEvent(ctx, audit.ActionQuestReward, audit.TargetUser, quest.UserId.String())
+	if err != nil {
+		return err
+	}
+	auditEvent.Metadata["quest_id"] = quest.Id.String()
+	auditEvent.Metadata["template_id"] = quest.TemplateId
+
+	if reward.Item != "" {
+		stack, err := inventory.NewStack(reward.Item, reward.Quantity)
+		if err != nil {
+			return err
+		}
+
+		if err := inventory.Grant(ctx, tx, quest.UserId, stack); err != nil {
+			return err
+		}
+
+		auditEvent.Metadata["item"] = stack.Item
+		auditEvent.Metadata["quantity"] = stack.Quantity
+	}
+
+	if reward.Experience > 0 {
+		party, err := userspokemon.FindParty(ctx, tx, quest.UserId)
+		if err != nil {
+			return err
+		}
+
+		var receiver *ulid.ULID
+		if len(party) > 0 {
+			receiver = &party[0].Id
+		} else if event.Kind != userspokemon.HistoryRelease {
+			receiver = &event.UserPokemonId
+		}
+
+		if receiver != nil {
+			if _, _, err := userspokemon.AwardExperience(ctx, tx, *receiver, reward.Experience); err != nil {
+				return err
+			}
+
+			auditEvent.Metadata["experience"] = reward.Experience
+			auditEvent.Metadata["user_pokemon_id"] = receiver.String()
+		}
+	}
+
+	return audit.Record(ctx, tx, auditEvent)
+}
+
+// listQuests shows the user's quests for today and this week, generating
+// them on first look.
+func listQuests(ctx context.Context, userId ulid.ULID) ([]Quest, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if _, err := ensureQuests(ctx, tx, userId, now); err != nil {
+		return nil, err
+	}
+
+	list, err := findCurrentQuests(ctx, tx, userId, periodStart(PeriodDaily, now), periodStart(PeriodWeekly, now))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,192 @@
+package quests
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/oklog/ulid/v2"
+	"hash/fnv"
+	"math/rand"
+	"mda/inventory"
+	"sort"
+	"time"
+)
+
+const (
+	PeriodDaily  = "daily"
+	PeriodWeekly = "weekly"
+)
+
+var Periods = []string{PeriodDaily, PeriodWeekly}
+
+// events mirrors the history kinds userspokemon records, as achievements
+// does.
+var events = map[string]bool{
+	"catch":     true,
+	"release":   true,
+	"unrelease": true,
+	"rename":    true,
+	"trade":     true,
+	"level_up":  true,
+	"evolve":    true,
+}
+
+//go:embed templates.json
+var defaultTemplates []byte
+
+var templates = mustParseTemplates(defaultTemplates)
+
+// Reward is what completing a quest pays out. Experience goes to the
+// user's party lead.
+type Reward struct {
+	Item       string `json:"item,omitempty"`
+	Quantity   int    `json:"quantity,omitempty"`
+	Experience int    `json:"experience,omitempty"`
+}
+
+// Template describes a quest that can be handed out each period. A quest
+// counts the owner's Pokémon events of one kind, optionally only for
+// species of a type as named by PokeAPI.
+type Template struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Period      string `json:"period"`
+	Event       string `json:"event"`
+	Type        string `json:"type,omitempty"`
+	Target      int    `json:"target"`
+	Reward      Reward `json:"reward"`
+}
+
+func (t Template) validate() error {
+	if t.Id == "" {
+		return ErrorTemplateIdRequired
+	}
+
+	if t.Period != PeriodDaily && t.Period != PeriodWeekly {
+		return fmt.Errorf("%s: %w", t.Id, ErrorTemplateUnknownPeriod)
+	}
+
+	if !events[t.Event] {
+		return fmt.Errorf("%s: %w", t.Id, ErrorTemplateUnknownEvent)
+	}
+
+	if t.Target <= 0 {
+		return fmt.Errorf("%s: %w", t.Id, ErrorTemplateInvalidTarget)
+	}
+
+	if t.Reward.Experience < 0 || (t.Reward.Item == "" && t.Reward.Experience == 0) {
+		return fmt.Errorf("%s: %w", t.Id, ErrorTemplateInvalidReward)
+	}
+
+	if t.Reward.Item != "" {
+		if _, err := inventory.NewStack(t.Reward.Item, t.Reward.Quantity); err != nil {
+			return fmt.Errorf("%s: %w: %v", t.Id, ErrorTemplateInvalidReward, err)
+		}
+	}
+
+	return nil
+}
+
+func parseTemplates(data []byte) ([]Template, error) {
+	var parsed []Template
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(parsed))
+	for _, template := range parsed {
+		if err := template.validate(); err != nil {
+			return nil, err
+		}
+
+		if seen[template.Id] {
+			return nil, fmt.Errorf("%s: %w", template.Id, ErrorTemplateDuplicate)
+		}
+		seen[template.Id] = true
+	}
+
+	return parsed, nil
+}
+
+func mustParseTemplates(data []byte) []Template {
+	parsed, err := parseTemplates(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+func findTemplate(id string) (Template, bool) {
+	for _, template := range templates {
+		if template.Id == id {
+			return template, true
+		}
+	}
+
+	return Template{}, false
+}
+
+// pickTemplates chooses n of the period's templates for a user. The choice
+// only depends on the user and the period start, so every replica that
+// generates the same quests picks the same ones.
+func pickTemplates(userId ulid.ULID, period string, start time.Time, n int) []Template {
+	var candidates []Template
+	for _, template := range templates {
+		if template.Period == period {
+			candidates = append(candidates, template)
+		}
+	}
+
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	h := fnv.New64a()
+	h.Write(userId[:])
+	h.Write([]byte(period))
+	binary.Write(h, binary.BigEndian, start.Unix())
+
+	rnd := rand.New(rand.NewSource(int64(h.Sum64())))
+	order := rnd.Perm(len(candidates))[:n]
+	sort.Ints(order)
+
+	picked := make([]Template, 0, n)
+	for _, i := range order {
+		picked = append(picked, candidates[i])
+	}
+
+	return picked
+}
+
+// periodStart is the UTC day, or the Monday of the ISO week, that t falls
+// in.
+func periodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	if period == PeriodWeekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+
+	return day
+}
+
+func periodEnd(period string, start time.Time) time.Time {
+	if period == PeriodWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+func periodCount(period string) int {
+	if period == PeriodWeekly {
+		return weeklyCount
+	}
+
+	return dailyCount
+}
@@ -0,0 +1,102 @@
+package realtime
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/oklog/ulid/v2"
+	"sync"
+	"time"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	maxMessageSize = 512
+)
+
+// client is one WebSocket connection. Events wait in a bounded queue; a
+// client that lets it fill up is disconnected rather than slowing the
+// publisher down, and can resume from its last event id.
+type client struct {
+	userId ulid.ULID
+	conn   *websocket.Conn
+	send   chan Event
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newClient(userId ulid.ULID, conn *websocket.Conn) *client {
+	return &client{
+		userId: userId,
+		conn:   conn,
+		send:   make(chan Event, sendQueueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues an event without blocking. It must not be called after the
+// client is unsubscribed.
+func (c *client) push(event Event) {
+	select {
+	case c.send <- event:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// readPump only services control frames: pongs keep the connection alive,
+// and a read error means the peer has gone.
+func (c *client) readPump() {
+	defer c.close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait()))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait()))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case event := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow, reconnect with last_event_id"),
+				time.Now().Add(writeWait),
+			)
+			return
+		}
+	}
+}
+
+func pongWait() time.Duration {
+	return pingInterval * 2
+}
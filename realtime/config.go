@@ -0,0 +1,24 @@
+package realtime
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	historySize   = 100
+	sendQueueSize = 64
+	pingInterval  = 30 * time.Second
+)
+
+func SetLimits(history, sendQueue int, ping time.Duration) error {
+	if history <= 0 || sendQueue <= 0 || ping <= 0 {
+		return errors.New("Realtime limits must be positive")
+	}
+
+	historySize = history
+	sendQueueSize = sendQueue
+	pingInterval = ping
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"github.com/oklog/ulid/v2"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	EventBattleMove      = "battle.move"
+	EventBattleFinished  = "battle.finished"
+	EventTradeOffered    = "trade.offered"
+	EventTradeCountered  = "trade.countered"
+	EventTradeAccepted   = "trade.accepted"
+	EventTradeDeclined   = "trade.declined"
+	EventTradeCancelled  = "trade.cancelled"
+	EventPokemonEvolved  = "pokemon.evolved"
+	EventFriendRequested = "friend.requested"
+	EventFriendAccepted  = "friend.accepted"
+
+	// EventResync tells a resuming client that events were missed and it
+	// should reload its state from the REST endpoints.
+	EventResync = "resync"
+)
+
+type Event struct {
+	Id        ulid.ULID   `json:"id"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+var (
+	entropy   = ulid.Monotonic(rand.New(rand.NewSource(time.Now().UnixNano())), 0)
+	entropyMu sync.Mutex
+)
+
+// NewEvent stamps an event with a ULID that sorts after every earlier one
+// from this process, which is what resuming relies on.
+func NewEvent(eventType string, data interface{}) (Event, error) {
+	entropyMu.Lock()
+	id, err := ulid.New(ulid.Timestamp(time.Now()), entropy)
+	entropyMu.Unlock()
+
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Id:        id,
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}, nil
+}
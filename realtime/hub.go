@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"github.com/oklog/ulid/v2"
+	"sync"
+)
+
+// hub keeps, per user, the connected clients and a ring of recent events
+// to replay to clients that reconnect. It lives in process memory only:
+// with several replicas an event reaches just the clients connected to the
+// instance that published it, and resuming only works against that same
+// instance. History is lost on restart.
+type hub struct {
+	mu      sync.Mutex
+	clients map[ulid.ULID]map[*client]struct{}
+	history map[ulid.ULID][]Event
+}
+
+var defaultHub = &hub{
+	clients: make(map[ulid.ULID]map[*client]struct{}),
+	history: make(map[ulid.ULID][]Event),
+}
+
+// Publish sends an event to every connection of a user and keeps it for
+// resuming. It never blocks on a slow connection.
+func Publish(userId ulid.ULID, eventType string, data interface{}) error {
+	event, err := NewEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	defaultHub.publish(userId, event)
+
+	return nil
+}
+
+func (h *hub) publish(userId ulid.ULID, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history := append(h.history[userId], event)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	h.history[userId] = history
+
+	for c := range h.clients[userId] {
+		c.push(event)
+	}
+}
+
+// subscribe registers a client and queues whatever it missed after lastId.
+// Holding the lock across both keeps a concurrent publish from slipping in
+// between the replay and the registration.
+func (h *hub) subscribe(c *client, lastId *ulid.ULID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[c.userId] == nil {
+		h.clients[c.userId] = make(map[*client]struct{})
+	}
+	h.clients[c.userId][c] = struct{}{}
+
+	if lastId == nil {
+		return
+	}
+
+	history := h.history[c.userId]
+	start := -1
+	for i, event := range history {
+		if event.Id == *lastId {
+			start = i + 1
+			break
+		}
+	}
+
+	if start < 0 {
+		// The event is older than what we keep, or from before a restart.
+		if resync, err := NewEvent(EventResync, nil); err == nil {
+			c.push(resync)
+		}
+		start = 0
+		for start < len(history) && history[start].Id.Compare(*lastId) <= 0 {
+			start++
+		}
+	}
+
+	for _, event := range history[start:] {
+		c.push(event)
+	}
+}
+
+func (h *hub) unsubscribe(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients[c.userId], c)
+	if len(h.clients[c.userId]) == 0 {
+		delete(h.clients, c.userId)
+	}
+}
@@ -0,0 +1,84 @@
+package realtime
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"net/http"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+func Router() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.StreamTokenAuth)
+	r.Get("/", connectHandler)
+
+	return r
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	var j struct {
+		Msg string `json:"message"`
+	}
+
+	j.Msg = msg
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(j)
+	if err != nil {
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeMessage(w, status, err.Error())
+}
+
+// connectHandler upgrades to a WebSocket that streams the user's events.
+// A reconnecting client passes the id of the last event it saw, as
+// ?last_event_id= or a Last-Event-ID header, to get what it missed.
+func connectHandler(w http.ResponseWriter, req *http.Request) {
+	userId, err := helper.CurrentUserId(req.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var lastId *ulid.ULID
+	last := req.URL.Query().Get("last_event_id")
+	if last == "" {
+		last = req.Header.Get("Last-Event-ID")
+	}
+
+	if last != "" {
+		id, err := ulid.Parse(last)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		lastId = &id
+	}
+
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// Upgrade has already written the error response.
+		return
+	}
+
+	c := newClient(userId, conn)
+	defaultHub.subscribe(c, lastId)
+
+	go c.writePump()
+
+	c.readPump()
+	defaultHub.unsubscribe(c)
+}
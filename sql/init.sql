@@ -20,4 +20,402 @@ CREATE TABLE IF NOT EXISTS users_pokemons (
 
     PRIMARY KEY(id),
     FOREIGN KEY(user_id) REFERENCES users(id)
-);
\ No newline at end of file
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email text UNIQUE;
+
+CREATE TABLE IF NOT EXISTS users_password_resets (
+    id         bytea       NOT NULL,
+    user_id    bytea       NOT NULL,
+    token_hash text        NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          bytea       NOT NULL,
+    action      text        NOT NULL,
+    actor_id    bytea,
+    target_type text        NOT NULL,
+    target_id   text        NOT NULL,
+    ip          text,
+    request_id  text,
+    metadata    jsonb       NOT NULL DEFAULT '{}',
+    created_at  timestamptz NOT NULL,
+
+    PRIMARY KEY(id)
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events(actor_id);
+
+CREATE TABLE IF NOT EXISTS users_items (
+    user_id  bytea NOT NULL,
+    item     text  NOT NULL,
+    quantity int   NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+
+    PRIMARY KEY(user_id, item),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users_items_allowances (
+    user_id    bytea NOT NULL,
+    claimed_on date  NOT NULL,
+
+    PRIMARY KEY(user_id, claimed_on),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS encounters (
+    id            bytea       NOT NULL,
+    user_id       bytea       NOT NULL,
+    pokemon_id    int         NOT NULL,
+    hp_percent    int         NOT NULL,
+    status        text        NOT NULL DEFAULT '',
+    attempts_left int         NOT NULL,
+    created_at    timestamptz NOT NULL,
+    expires_at    timestamptz NOT NULL,
+    closed_at     timestamptz,
+    outcome       text,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS encounters_user_id_idx ON encounters(user_id);
+
+CREATE TABLE IF NOT EXISTS trades (
+    id           bytea       NOT NULL,
+    proposer_id  bytea       NOT NULL,
+    recipient_id bytea       NOT NULL,
+    status       text        NOT NULL,
+    counter_of   bytea,
+    created_at   timestamptz NOT NULL,
+    expires_at   timestamptz NOT NULL,
+    resolved_at  timestamptz,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY(proposer_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(recipient_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(counter_of) REFERENCES trades(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS trades_pokemons (
+    trade_id        bytea NOT NULL,
+    user_pokemon_id bytea NOT NULL,
+    owner_id        bytea NOT NULL,
+
+    PRIMARY KEY(trade_id, user_pokemon_id),
+    FOREIGN KEY(trade_id) REFERENCES trades(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_pokemon_id) REFERENCES users_pokemons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users_boxes (
+    id         bytea       NOT NULL,
+    user_id    bytea       NOT NULL,
+    name       text        NOT NULL,
+    capacity   integer     NOT NULL CHECK (capacity > 0),
+    position   integer     NOT NULL,
+    created_at timestamptz NOT NULL,
+
+    PRIMARY KEY(id),
+    UNIQUE(user_id, position),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS party_slot smallint CHECK (party_slot BETWEEN 1 AND 6);
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS box_id bytea REFERENCES users_boxes(id) ON DELETE SET NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_pokemons_party_slot_key') THEN
+        ALTER TABLE users_pokemons ADD CONSTRAINT users_pokemons_party_slot_key
+            UNIQUE (user_id, party_slot) DEFERRABLE INITIALLY DEFERRED;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_pokemons_single_location') THEN
+        ALTER TABLE users_pokemons ADD CONSTRAINT users_pokemons_single_location
+            CHECK (party_slot IS NULL OR box_id IS NULL);
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS users_pokemons_box_id_idx ON users_pokemons(box_id);
+
+ALTER TABLE encounters ADD COLUMN IF NOT EXISTS level smallint NOT NULL DEFAULT 5;
+
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS level smallint NOT NULL DEFAULT 1 CHECK (level BETWEEN 1 AND 100);
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS experience integer NOT NULL DEFAULT 0 CHECK (experience >= 0);
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS ivs integer[] NOT NULL DEFAULT '{0,0,0,0,0,0}';
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS evs integer[] NOT NULL DEFAULT '{0,0,0,0,0,0}';
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS nature text NOT NULL DEFAULT 'hardy';
+
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS friendship smallint NOT NULL DEFAULT 70 CHECK (friendship BETWEEN 0 AND 255);
+
+CREATE TABLE IF NOT EXISTS battles (
+    id            bytea       NOT NULL,
+    challenger_id bytea       NOT NULL,
+    opponent_id   bytea       NOT NULL,
+    seed          bigint      NOT NULL,
+    sides         jsonb       NOT NULL,
+    winner_id     bytea,
+    turns         integer     NOT NULL,
+    log           jsonb       NOT NULL,
+    rewards       jsonb       NOT NULL,
+    created_at    timestamptz NOT NULL,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY(challenger_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(opponent_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS battles_challenger_id_idx ON battles(challenger_id);
+CREATE INDEX IF NOT EXISTS battles_opponent_id_idx ON battles(opponent_id);
+
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS rename_count integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS users_release_gates (
+    user_id    bytea       NOT NULL,
+    attempts   integer     NOT NULL,
+    threshold  integer     NOT NULL,
+    updated_at timestamptz NOT NULL,
+
+    PRIMARY KEY(user_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users_pokemons_events (
+    id              bytea       NOT NULL,
+    user_pokemon_id bytea       NOT NULL,
+    kind            text        NOT NULL,
+    owner_id        bytea       NOT NULL,
+    actor_id        bytea,
+    before          jsonb       NOT NULL DEFAULT '{}',
+    after           jsonb       NOT NULL DEFAULT '{}',
+    created_at      timestamptz NOT NULL,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY(user_pokemon_id) REFERENCES users_pokemons(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS users_pokemons_events_user_pokemon_id_idx ON users_pokemons_events(user_pokemon_id);
+CREATE INDEX IF NOT EXISTS users_pokemons_events_owner_id_idx ON users_pokemons_events(owner_id);
+CREATE INDEX IF NOT EXISTS users_pokemons_events_actor_id_idx ON users_pokemons_events(actor_id);
+
+CREATE TABLE IF NOT EXISTS pokemon_species (
+    id         int         NOT NULL,
+    name       text        NOT NULL,
+    generation text        NOT NULL,
+    types      text[]      NOT NULL DEFAULT '{}',
+    synced_at  timestamptz NOT NULL,
+
+    PRIMARY KEY(id)
+);
+
+CREATE INDEX IF NOT EXISTS users_pokemons_user_id_pokemon_id_idx ON users_pokemons(user_id, pokemon_id);
+
+ALTER TABLE users_pokemons_events ADD COLUMN IF NOT EXISTS pokemon_id int NOT NULL DEFAULT 0;
+
+-- Events written before the column existed still carry the species in their snapshot.
+UPDATE users_pokemons_events SET pokemon_id = (after->>'pokemon_id')::int
+ WHERE pokemon_id = 0 AND after ? 'pokemon_id';
+
+CREATE TABLE IF NOT EXISTS leaderboard_scores (
+    user_id      bytea       NOT NULL,
+    metric       text        NOT NULL,
+    period       text        NOT NULL,
+    period_start date        NOT NULL,
+    score        bigint      NOT NULL,
+    pokemon_id   int,
+    updated_at   timestamptz NOT NULL,
+
+    PRIMARY KEY(metric, period, period_start, user_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS leaderboard_scores_user_id_idx ON leaderboard_scores(user_id);
+
+CREATE TABLE IF NOT EXISTS leaderboard_species (
+    user_id      bytea NOT NULL,
+    period       text  NOT NULL,
+    period_start date  NOT NULL,
+    pokemon_id   int   NOT NULL,
+
+    PRIMARY KEY(user_id, period, period_start, pokemon_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Seed the all-time boards from what is already stored. Daily and weekly
+-- boards and rarest_catch, which needs capture rates from PokeAPI, fill up
+-- as new events come in. Reruns leave existing scores alone.
+INSERT INTO leaderboard_species (user_id, period, period_start, pokemon_id)
+SELECT DISTINCT user_id, 'all_time', date '1970-01-01', pokemon_id
+  FROM users_pokemons
+ WHERE user_id IN (SELECT id FROM users)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO leaderboard_scores (user_id, metric, period, period_start, score, updated_at)
+SELECT user_id, 'catches', 'all_time', date '1970-01-01', count(*), max(captured_at)
+  FROM users_pokemons
+ WHERE user_id IN (SELECT id FROM users)
+ GROUP BY user_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO leaderboard_scores (user_id, metric, period, period_start, score, updated_at)
+SELECT user_id, 'unique_species', 'all_time', date '1970-01-01', count(DISTINCT pokemon_id), max(captured_at)
+  FROM users_pokemons
+ WHERE user_id IN (SELECT id FROM users)
+ GROUP BY user_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO leaderboard_scores (user_id, metric, period, period_start, score, updated_at)
+SELECT winner_id, 'battle_wins', 'all_time', date '1970-01-01', count(*), max(created_at)
+  FROM battles
+ WHERE winner_id IS NOT NULL
+ GROUP BY winner_id
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS users_achievements (
+    user_id        bytea       NOT NULL,
+    achievement_id text        NOT NULL,
+    unlocked_at    timestamptz NOT NULL,
+
+    PRIMARY KEY(user_id, achievement_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS users_pokemons_events_owner_id_kind_idx ON users_pokemons_events(owner_id, kind);
+
+CREATE TABLE IF NOT EXISTS users_friendships (
+    user_id     bytea       NOT NULL,
+    friend_id   bytea       NOT NULL,
+    status      text        NOT NULL,
+    created_at  timestamptz NOT NULL,
+    accepted_at timestamptz,
+
+    PRIMARY KEY(user_id, friend_id),
+    CHECK (user_id <> friend_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(friend_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_friendships_pair_idx
+    ON users_friendships (LEAST(user_id, friend_id), GREATEST(user_id, friend_id));
+CREATE INDEX IF NOT EXISTS users_friendships_friend_id_idx ON users_friendships(friend_id);
+
+CREATE TABLE IF NOT EXISTS users_blocks (
+    user_id    bytea       NOT NULL,
+    blocked_id bytea       NOT NULL,
+    created_at timestamptz NOT NULL,
+
+    PRIMARY KEY(user_id, blocked_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS users_blocks_blocked_id_idx ON users_blocks(blocked_id);
+
+CREATE TABLE IF NOT EXISTS users_privacy (
+    user_id    bytea       NOT NULL,
+    profile    text        NOT NULL,
+    collection text        NOT NULL,
+    pokedex    text        NOT NULL,
+    trades     text        NOT NULL,
+    battles    text        NOT NULL,
+    updated_at timestamptz NOT NULL,
+
+    PRIMARY KEY(user_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users_quests_generations (
+    user_id      bytea       NOT NULL,
+    period       text        NOT NULL,
+    period_start date        NOT NULL,
+    generated_at timestamptz NOT NULL,
+
+    PRIMARY KEY(user_id, period, period_start),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users_quests (
+    id           bytea       NOT NULL,
+    user_id      bytea       NOT NULL,
+    template_id  text        NOT NULL,
+    period       text        NOT NULL,
+    period_start date        NOT NULL,
+    progress     int         NOT NULL DEFAULT 0,
+    target       int         NOT NULL,
+    completed_at timestamptz,
+    created_at   timestamptz NOT NULL,
+
+    PRIMARY KEY(id),
+    UNIQUE(user_id, period, period_start, template_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS shiny boolean NOT NULL DEFAULT false;
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS gender text NOT NULL DEFAULT 'unknown' CHECK (gender IN ('male', 'female', 'genderless', 'unknown'));
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS ability_slot smallint NOT NULL DEFAULT 1 CHECK (ability_slot BETWEEN 1 AND 3);
+
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS favorite boolean NOT NULL DEFAULT false;
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+ALTER TABLE users_pokemons ADD COLUMN IF NOT EXISTS note text NOT NULL DEFAULT '' CHECK (char_length(note) <= 500);
+
+-- Emails are looked up case-insensitively, so they must be unique that way
+-- too. Accounts that only differ from an older one by the case of their
+-- email lose it: the older account keeps password resets.
+UPDATE users u SET email = NULL
+ WHERE email IS NOT NULL
+   AND EXISTS (SELECT 1 FROM users o WHERE lower(o.email) = lower(u.email) AND o.id < u.id);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
+
+-- Failed logins used to keep the username as typed, which is often a
+-- mistyped password.
+UPDATE audit_events SET metadata = metadata - 'username' WHERE action = 'auth.login_failure';
+
+-- Pokémon caught before parties and boxes existed have neither a party slot
+-- nor a box. Place them the way a catch does, oldest first: the party fills
+-- up to six, the rest go into new boxes of the default capacity of 30.
+-- Reruns find nothing left to place.
+WITH unplaced AS (
+    SELECT id, user_id, row_number() OVER (PARTITION BY user_id ORDER BY captured_at, id) AS rn
+      FROM users_pokemons
+     WHERE NOT released AND party_slot IS NULL AND box_id IS NULL
+), taken AS (
+    SELECT user_id, max(party_slot) AS last
+      FROM users_pokemons
+     WHERE party_slot IS NOT NULL
+     GROUP BY user_id
+)
+UPDATE users_pokemons p SET party_slot = COALESCE(t.last, 0) + u.rn
+  FROM unplaced u LEFT JOIN taken t ON t.user_id = u.user_id
+ WHERE p.id = u.id AND COALESCE(t.last, 0) + u.rn <= 6;
+
+WITH unplaced AS (
+    SELECT id, user_id, (row_number() OVER (PARTITION BY user_id ORDER BY captured_at, id) - 1) / 30 AS box_index
+      FROM users_pokemons
+     WHERE NOT released AND party_slot IS NULL AND box_id IS NULL
+), boxes AS (
+    SELECT u.user_id, u.box_index,
+           COALESCE((SELECT max(position) FROM users_boxes b WHERE b.user_id = u.user_id), 0) + u.box_index + 1 AS position
+      FROM (SELECT DISTINCT user_id, box_index FROM unplaced) u
+), created AS (
+    -- Box ids are ULIDs: 48 bits of milliseconds, then 80 random bits.
+    INSERT INTO users_boxes (id, user_id, name, capacity, position, created_at)
+    SELECT decode(lpad(to_hex((extract(epoch FROM now()) * 1000)::bigint), 12, '0'), 'hex')
+               || substring(decode(md5(random()::text || encode(user_id, 'hex') || position), 'hex') FROM 1 FOR 10),
+           user_id, 'Box ' || position, 30, position, now()
+      FROM boxes
+    RETURNING id, user_id, position
+)
+UPDATE users_pokemons p SET box_id = c.id
+  FROM unplaced u
+  JOIN boxes b ON b.user_id = u.user_id AND b.box_index = u.box_index
+  JOIN created c ON c.user_id = b.user_id AND c.position = b.position
+ WHERE p.id = u.id;
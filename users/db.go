@@ -3,12 +3,33 @@ package users
 import (
 	"errors"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"mda/mailer"
+	"time"
 )
 
 var (
 	pool *pgxpool.Pool
 
-	ErrorUserNotFound = errors.New("user not found")
+	mail          mailer.Mailer = mailer.NewLogMailer()
+	resetTokenTTL               = time.Hour
+
+	ErrorUserNotFound      = errors.New("user not found")
+	ErrorUserNotDeleted    = errors.New("user is not deleted")
+	ErrorUserAnonymized    = errors.New("user has been anonymized")
+	ErrorInvalidRole       = errors.New("invalid role")
+	ErrorEmptyPassword     = errors.New("password cannot be empty")
+	ErrorInvalidResetToken = errors.New("invalid or expired reset token")
+
+	ErrorFriendSelf            = errors.New("cannot befriend yourself")
+	ErrorFriendRequestNotFound = errors.New("friend request not found")
+	ErrorFriendRequestExists   = errors.New("friend request already sent")
+	ErrorAlreadyFriends        = errors.New("already friends")
+	ErrorFriendNotFound        = errors.New("friend not found")
+	ErrorBlockSelf             = errors.New("cannot block yourself")
+	ErrorUserBlocked           = errors.New("user is blocked")
+	ErrorNotAllowed            = errors.New("not allowed by the user's privacy settings")
+	ErrorInvalidAspect         = errors.New("privacy setting must be one of profile, collection, pokedex, trades or battles")
+	ErrorInvalidAudience       = errors.New("audience must be everyone, friends or nobody")
 )
 
 func SetPool(newPool *pgxpool.Pool) error {
@@ -20,3 +41,23 @@ func SetPool(newPool *pgxpool.Pool) error {
 
 	return nil
 }
+
+func SetMailer(newMailer mailer.Mailer) error {
+	if newMailer == nil {
+		return errors.New("Cannot assign nil mailer")
+	}
+
+	mail = newMailer
+
+	return nil
+}
+
+func SetResetTokenTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("Reset token TTL must be positive")
+	}
+
+	resetTokenTTL = ttl
+
+	return nil
+}
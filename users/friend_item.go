@@ -0,0 +1,56 @@
+package users
+
+import (
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+	"time"
+)
+
+const (
+	FriendPending  = "pending"
+	FriendAccepted = "accepted"
+)
+
+// Friendship links two users. UserId sent the request and FriendId
+// received it; once accepted the link is mutual.
+type Friendship struct {
+	UserId     ulid.ULID
+	FriendId   ulid.ULID
+	Status     string
+	CreatedAt  time.Time
+	AcceptedAt null.Time
+}
+
+func NewFriendRequest(from, to ulid.ULID) (Friendship, error) {
+	if from == to {
+		return Friendship{}, ErrorFriendSelf
+	}
+
+	return Friendship{
+		UserId:    from,
+		FriendId:  to,
+		Status:    FriendPending,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// AcceptFriendRequest lets the receiver of a pending request accept it.
+func AcceptFriendRequest(f *Friendship, userId ulid.ULID) error {
+	if f.Status != FriendPending || f.FriendId != userId {
+		return ErrorFriendRequestNotFound
+	}
+
+	f.Status = FriendAccepted
+	f.AcceptedAt = null.TimeFrom(time.Now())
+
+	return nil
+}
+
+// other is the user on the far side of the friendship from userId.
+func (f Friendship) other(userId ulid.ULID) ulid.ULID {
+	if f.UserId == userId {
+		return f.FriendId
+	}
+
+	return f.UserId
+}
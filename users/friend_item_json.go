@@ -0,0 +1,25 @@
+package users
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (f Friendship) MarshalJSON() ([]byte, error) {
+	var j struct {
+		UserId     ulid.ULID  `json:"user_id"`
+		FriendId   ulid.ULID  `json:"friend_id"`
+		Status     string     `json:"status"`
+		CreatedAt  time.Time  `json:"created_at"`
+		AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	}
+
+	j.UserId = f.UserId
+	j.FriendId = f.FriendId
+	j.Status = f.Status
+	j.CreatedAt = f.CreatedAt
+	j.AcceptedAt = f.AcceptedAt.Ptr()
+
+	return json.Marshal(j)
+}
@@ -0,0 +1,61 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+	"time"
+)
+
+const resetTokenBytes = 32
+
+type PasswordReset struct {
+	Id        ulid.ULID
+	UserId    ulid.ULID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    null.Time
+}
+
+// NewPasswordReset returns the reset record together with the raw token. Only
+// the hash of the token is stored, the raw value is what gets mailed out.
+func NewPasswordReset(userId ulid.ULID, ttl time.Duration) (PasswordReset, string, error) {
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return PasswordReset{}, "", err
+	}
+
+	raw := make([]byte, resetTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return PasswordReset{}, "", err
+	}
+
+	token := hex.EncodeToString(raw)
+	now := time.Now()
+
+	return PasswordReset{
+		Id:        id,
+		UserId:    userId,
+		TokenHash: hashResetToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, token, nil
+}
+
+func UsePasswordReset(reset *PasswordReset) error {
+	if reset.UsedAt.Valid || time.Now().After(reset.ExpiresAt) {
+		return ErrorInvalidResetToken
+	}
+
+	reset.UsedAt = null.TimeFrom(time.Now())
+
+	return nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+const (
+	AudienceEveryone = "everyone"
+	AudienceFriends  = "friends"
+	AudienceNobody   = "nobody"
+)
+
+// Aspects of a user that others may see or act on. Each one has its own
+// audience in Privacy.
+const (
+	AspectProfile    = "profile"
+	AspectCollection = "collection"
+	AspectPokedex    = "pokedex"
+	AspectTrades     = "trades"
+	AspectBattles    = "battles"
+)
+
+// Privacy says who may view a user's profile, collection and Pokédex, and
+// who may offer them trades or challenge them to battles.
+type Privacy struct {
+	UserId     ulid.ULID
+	Profile    string
+	Collection string
+	Pokedex    string
+	Trades     string
+	Battles    string
+	UpdatedAt  time.Time
+}
+
+// DefaultPrivacy applies to users who never changed their settings.
+func DefaultPrivacy(userId ulid.ULID) Privacy {
+	return Privacy{
+		UserId:     userId,
+		Profile:    AudienceEveryone,
+		Collection: AudienceFriends,
+		Pokedex:    AudienceFriends,
+		Trades:     AudienceEveryone,
+		Battles:    AudienceEveryone,
+	}
+}
+
+func (p *Privacy) field(aspect string) *string {
+	switch aspect {
+	case AspectProfile:
+		return &p.Profile
+	case AspectCollection:
+		return &p.Collection
+	case AspectPokedex:
+		return &p.Pokedex
+	case AspectTrades:
+		return &p.Trades
+	case AspectBattles:
+		return &p.Battles
+	}
+
+	return nil
+}
+
+func (p Privacy) Audience(aspect string) string {
+	if f := p.field(aspect); f != nil {
+		return *f
+	}
+
+	return AudienceNobody
+}
+
+// UpdatePrivacy sets the audience of each aspect in changes. Nothing
+// changes if any aspect or audience is unknown.
+func UpdatePrivacy(p *Privacy, changes map[string]string) error {
+	updated := *p
+
+	for aspect, audience := range changes {
+		f := updated.field(aspect)
+		if f == nil {
+			return ErrorInvalidAspect
+		}
+
+		switch audience {
+		case AudienceEveryone, AudienceFriends, AudienceNobody:
+			*f = audience
+		default:
+			return ErrorInvalidAudience
+		}
+	}
+
+	updated.UpdatedAt = time.Now()
+	*p = updated
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package users
+
+import "encoding/json"
+
+func (p Privacy) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Profile    string `json:"profile"`
+		Collection string `json:"collection"`
+		Pokedex    string `json:"pokedex"`
+		Trades     string `json:"trades"`
+		Battles    string `json:"battles"`
+	}
+
+	j.Profile = p.Profile
+	j.Collection = p.Collection
+	j.Pokedex = p.Pokedex
+	j.Trades = p.Trades
+	j.Battles = p.Battles
+
+	return json.Marshal(j)
+}
@@ -0,0 +1,38 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StartPurgeJob permanently removes users that were soft deleted longer than
+// retention ago. It runs once immediately and then on every interval until ctx
+// is cancelled. A zero retention disables the job.
+func StartPurgeJob(ctx context.Context, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		log.Info().Msg("user purge job disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			count, err := purgeExpiredUsers(ctx, retention)
+			if err != nil {
+				log.Error().Err(err).Msg("cannot purge deleted users")
+			} else if count > 0 {
+				log.Info().Int64("count", count).Msg("purged deleted users")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
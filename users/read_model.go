@@ -2,38 +2,115 @@ package users
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
 	"gopkg.in/guregu/null.v4"
+	"mda/achievements"
+	"mda/audit"
+	"strings"
 	"time"
 )
 
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 type UserList struct {
-	Users []User `json:"users"`
-	Count int    `json:"count"`
+	Users      []User     `json:"users"`
+	Count      int        `json:"count"`
+	NextCursor *ulid.ULID `json:"next_cursor,omitempty"`
+}
+
+// UserFilter drives the admin listing. Users are paged with a keyset on the
+// ULID primary key, which sorts the same way as created_at.
+type UserFilter struct {
+	Limit          int
+	Cursor         ulid.ULID
+	Descending     bool
+	Role           string
+	Query          string
+	IncludeDeleted bool
 }
 
 var emptyList UserList
 
-func findAllUsers(ctx context.Context, tx pgx.Tx) (UserList, error) {
+func (f UserFilter) where(withCursor bool) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if !f.IncludeDeleted {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+
+	if f.Role != "" {
+		args = append(args, f.Role)
+		conds = append(conds, fmt.Sprintf("role = $%d", len(args)))
+	}
+
+	if f.Query != "" {
+		args = append(args, "%"+escapeLike(f.Query)+"%")
+		conds = append(conds, fmt.Sprintf("username ILIKE $%d", len(args)))
+	}
+
+	if withCursor && f.Cursor != (ulid.ULID{}) {
+		args = append(args, f.Cursor)
+		if f.Descending {
+			conds = append(conds, fmt.Sprintf("id < $%d", len(args)))
+		} else {
+			conds = append(conds, fmt.Sprintf("id > $%d", len(args)))
+		}
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// findAllUsers expects tx to be a repeatable read transaction so the count and
+// the page are taken from the same snapshot.
+func findAllUsers(ctx context.Context, tx pgx.Tx, filter UserFilter) (UserList, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
 	var userCount int
 
-	row := tx.QueryRow(ctx, "SELECT COUNT(id) FROM users WHERE deleted_at IS NULL")
+	where, args := filter.where(false)
+	row := tx.QueryRow(ctx, "SELECT COUNT(id) FROM users"+where, args...)
 	err := row.Scan(&userCount)
 
 	if err != nil {
 		return emptyList, err
 	}
 
-	if userCount == 0 {
-		return emptyList, nil
+	order := "ASC"
+	if filter.Descending {
+		order = "DESC"
 	}
 
-	users := make([]User, userCount)
+	where, args = filter.where(true)
+	args = append(args, limit+1)
 
 	rows, err := tx.Query(
 		ctx,
-		"SELECT id, username, password, role, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL",
+		fmt.Sprintf(
+			"SELECT id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at FROM users%s ORDER BY id %s LIMIT $%d",
+			where, order, len(args),
+		),
+		args...,
 	)
 
 	if err != nil {
@@ -42,38 +119,297 @@ func findAllUsers(ctx context.Context, tx pgx.Tx) (UserList, error) {
 
 	defer rows.Close()
 
-	var i int
+	users := make([]User, 0, limit)
 
-	for i = range users {
-		var id ulid.ULID
-		var username, password string
-		var role string
-		var createdAt time.Time
-		var updatedAt, deletedAt null.Time
+	for rows.Next() {
+		var user User
 
-		if !rows.Next() {
-			break
+		if err := rows.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.AnonymizedAt); err != nil {
+			return emptyList, err
 		}
 
-		if err := rows.Scan(&id, &username, &password, &role, &createdAt, &updatedAt, &deletedAt); err != nil {
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return emptyList, err
+	}
+
+	list := UserList{
+		Users: users,
+		Count: userCount,
+	}
+
+	if len(users) > limit {
+		list.Users = users[:limit]
+		next := users[limit-1].Id
+		list.NextCursor = &next
+	}
+
+	return list, nil
+}
+
+func findDeletedUsers(ctx context.Context, tx pgx.Tx) (UserList, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC",
+	)
+
+	if err != nil {
+		return emptyList, err
+	}
+
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		var user User
+
+		if err := rows.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.AnonymizedAt); err != nil {
 			return emptyList, err
 		}
 
-		users[i] = User{
-			Id:        id,
-			Username:  username,
-			Password:  password,
-			Role:      role,
-			CreatedAt: createdAt,
-			UpdatedAt: updatedAt,
-			DeletedAt: deletedAt,
-		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return emptyList, err
 	}
 
 	list := UserList{
 		Users: users,
-		Count: userCount,
+		Count: len(users),
 	}
 
 	return list, nil
 }
+
+// Profile is what a user sees about themselves: their account and the
+// badges they have unlocked.
+type Profile struct {
+	User   User
+	Badges []achievements.Badge
+}
+
+func (p Profile) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(p.User)
+	if err != nil {
+		return nil, err
+	}
+
+	var j map[string]json.RawMessage
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+
+	j["badges"], err = json.Marshal(p.Badges)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(j)
+}
+
+type Friend struct {
+	Id       ulid.ULID `json:"id"`
+	Username string    `json:"username"`
+	Since    time.Time `json:"since"`
+}
+
+// FriendRequest is a pending request as seen by one side of it. Direction
+// is incoming or outgoing.
+type FriendRequest struct {
+	UserId    ulid.ULID `json:"user_id"`
+	Username  string    `json:"username"`
+	Direction string    `json:"direction"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BlockedUser struct {
+	Id        ulid.ULID `json:"id"`
+	Username  string    `json:"username"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// PublicProfile is what other users may see of someone's profile.
+type PublicProfile struct {
+	Id        ulid.ULID            `json:"id"`
+	Username  string               `json:"username"`
+	CreatedAt time.Time            `json:"created_at"`
+	IsFriend  bool                 `json:"is_friend"`
+	Badges    []achievements.Badge `json:"badges"`
+}
+
+type ExportedSocial struct {
+	Friends  []Friend        `json:"friends"`
+	Requests []FriendRequest `json:"requests"`
+	Blocks   []BlockedUser   `json:"blocks"`
+	Privacy  Privacy         `json:"privacy"`
+}
+
+type ExportedAccount struct {
+	Id        ulid.ULID  `json:"id"`
+	Username  string     `json:"username"`
+	Email     *string    `json:"email,omitempty"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type ExportedPokemon struct {
+	Id         ulid.ULID `json:"id"`
+	PokemonId  int       `json:"pokemon_id"`
+	Nickname   string    `json:"nickname"`
+	CapturedAt time.Time `json:"captured_at"`
+	Released   bool      `json:"released"`
+	Favorite   bool      `json:"favorite"`
+	Tags       []string  `json:"tags"`
+	Note       string    `json:"note"`
+}
+
+type ExportedPokemonEvent struct {
+	Id            ulid.ULID              `json:"id"`
+	UserPokemonId ulid.ULID              `json:"user_pokemon_id"`
+	Kind          string                 `json:"kind"`
+	Before        map[string]interface{} `json:"before"`
+	After         map[string]interface{} `json:"after"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+type ExportedPasswordReset struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// UserExport is everything the service stores about a single person, as
+// returned to them on a data subject request.
+type UserExport struct {
+	ExportedAt     time.Time               `json:"exported_at"`
+	Account        ExportedAccount         `json:"account"`
+	Pokemons       []ExportedPokemon       `json:"pokemons"`
+	PokemonEvents  []ExportedPokemonEvent  `json:"pokemon_events"`
+	Badges         []achievements.Badge    `json:"badges"`
+	Social         ExportedSocial          `json:"social"`
+	PasswordResets []ExportedPasswordReset `json:"password_resets"`
+	AuditEvents    []audit.Event           `json:"audit_events"`
+}
+
+type exportPart struct {
+	Name  string
+	Value interface{}
+}
+
+func (e UserExport) parts() []exportPart {
+	return []exportPart{
+		{Name: "account.json", Value: e.Account},
+		{Name: "pokemons.json", Value: e.Pokemons},
+		{Name: "pokemon_events.json", Value: e.PokemonEvents},
+		{Name: "badges.json", Value: e.Badges},
+		{Name: "social.json", Value: e.Social},
+		{Name: "password_resets.json", Value: e.PasswordResets},
+		{Name: "audit_events.json", Value: e.AuditEvents},
+	}
+}
+
+func newExportedAccount(user User) ExportedAccount {
+	return ExportedAccount{
+		Id:        user.Id,
+		Username:  user.Username,
+		Email:     user.Email.Ptr(),
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt.Ptr(),
+	}
+}
+
+// findExportedPokemons reads users_pokemons directly, released rows included,
+// so this package doesn't have to depend on userspokemon.
+func findExportedPokemons(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]ExportedPokemon, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT id, pokemon_id, trim(nickname), captured_at, released, favorite, tags, note FROM users_pokemons WHERE user_id = $1 ORDER BY id",
+		userId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	pokemons := []ExportedPokemon{}
+
+	for rows.Next() {
+		var p ExportedPokemon
+
+		if err := rows.Scan(&p.Id, &p.PokemonId, &p.Nickname, &p.CapturedAt, &p.Released, &p.Favorite, &p.Tags, &p.Note); err != nil {
+			return nil, err
+		}
+
+		pokemons = append(pokemons, p)
+	}
+
+	return pokemons, rows.Err()
+}
+
+// findExportedPokemonEvents returns the history of every Pokémon the user
+// owned or acted on, including ones since traded away.
+func findExportedPokemonEvents(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]ExportedPokemonEvent, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT id, user_pokemon_id, kind, before, after, created_at FROM users_pokemons_events WHERE owner_id = $1 OR actor_id = $1 ORDER BY id",
+		userId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []ExportedPokemonEvent{}
+
+	for rows.Next() {
+		var e ExportedPokemonEvent
+
+		if err := rows.Scan(&e.Id, &e.UserPokemonId, &e.Kind, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func findExportedPasswordResets(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]ExportedPasswordReset, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT created_at, expires_at, used_at FROM users_password_resets WHERE user_id = $1 ORDER BY created_at",
+		userId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resets := []ExportedPasswordReset{}
+
+	for rows.Next() {
+		var r ExportedPasswordReset
+		var usedAt null.Time
+
+		if err := rows.Scan(&r.CreatedAt, &r.ExpiresAt, &usedAt); err != nil {
+			return nil, err
+		}
+
+		r.UsedAt = usedAt.Ptr()
+		resets = append(resets, r)
+	}
+
+	return resets, rows.Err()
+}
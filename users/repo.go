@@ -5,10 +5,11 @@ import (
 	"errors"
 	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
+	"time"
 )
 
 func findUserById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (User, error) {
-	query := `SELECT id, username, password, role, created_at, updated_at, deleted_at 
+	query := `SELECT id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at 
 				FROM users WHERE id = $1 
  			  AND deleted_at IS NULL`
 
@@ -16,7 +17,7 @@ func findUserById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (User, error) {
 
 	var user User
 	if err := row.Scan(
-		&user.Id, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt); err != nil {
+		&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.AnonymizedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return User{}, ErrorUserNotFound
 		}
@@ -26,15 +27,68 @@ func findUserById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (User, error) {
 	return user, nil
 }
 
+func findDeletedUserById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (User, error) {
+	query := `SELECT id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at 
+				FROM users WHERE id = $1 
+ 			  AND deleted_at IS NOT NULL`
+
+	row := tx.QueryRow(ctx, query, id)
+
+	var user User
+	if err := row.Scan(
+		&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.AnonymizedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return User{}, ErrorUserNotFound
+		}
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// findUserIdByUsername is for telling which account a failed login was
+// aimed at, without keeping what was typed.
+func findUserIdByUsername(ctx context.Context, tx pgx.Tx, username string) (ulid.ULID, error) {
+	query := `SELECT id FROM users WHERE username = $1 AND deleted_at IS NULL`
+
+	var id ulid.ULID
+	if err := tx.QueryRow(ctx, query, username).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ulid.ULID{}, ErrorUserNotFound
+		}
+		return ulid.ULID{}, err
+	}
+
+	return id, nil
+}
+
 func findUserByUsernameAndPassword(ctx context.Context, tx pgx.Tx, username, password string) (User, error) {
-	query := `SELECT id, username, password, role, created_at, updated_at, deleted_at 
+	query := `SELECT id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at 
 				FROM users WHERE username = $1 AND password = $2
 			  AND deleted_at IS NULL`
 
 	row := tx.QueryRow(ctx, query, username, password)
 
 	var user User
-	if err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt); err != nil {
+	if err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.AnonymizedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return User{}, ErrorUserNotFound
+		}
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func findUserByEmail(ctx context.Context, tx pgx.Tx, email string) (User, error) {
+	query := `SELECT id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at 
+				FROM users WHERE lower(email) = lower($1)
+			  AND deleted_at IS NULL`
+
+	row := tx.QueryRow(ctx, query, email)
+
+	var user User
+	if err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.AnonymizedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return User{}, ErrorUserNotFound
 		}
@@ -56,20 +110,174 @@ func checkAdminExists(ctx context.Context, tx pgx.Tx) (bool, error) {
 }
 
 func saveUser(ctx context.Context, tx pgx.Tx, user User) error {
-	query := `INSERT INTO users (id, username, password, role, created_at, updated_at, deleted_at) 
-					VALUES ($1, $2, $3, $4, $5, $6, $7)
+	query := `INSERT INTO users (id, username, password, email, role, created_at, updated_at, deleted_at, anonymized_at) 
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 			  ON CONFLICT (id) DO UPDATE SET
 					username = $2,
 					password = $3,
+					email = $4,
+					role = $5,
 					updated_at = COALESCE(EXCLUDED.updated_at, users.updated_at),
-					deleted_at = 
-						CASE WHEN EXCLUDED.deleted_at IS NOT NULL 
-					THEN EXCLUDED.deleted_at ELSE users.deleted_at END;`
+					deleted_at = EXCLUDED.deleted_at,
+					anonymized_at = EXCLUDED.anonymized_at;`
+
+	_, err := tx.Exec(ctx, query, user.Id, user.Username, user.Password, user.Email, user.Role, user.CreatedAt, user.UpdatedAt, user.DeletedAt, user.AnonymizedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// clearPokemonNotes wipes the free text a user wrote on their Pokémon,
+// tags included. The Pokémon themselves stay.
+func clearPokemonNotes(ctx context.Context, tx pgx.Tx, id ulid.ULID) error {
+	_, err := tx.Exec(ctx, `UPDATE users_pokemons SET note = '', tags = '{}' WHERE user_id = $1`, id)
+
+	return err
+}
+
+// resetNicknames gives the user's Pokémon back their species name. A
+// species missing from the catalogue, which only happens before its first
+// sync, is left without a nickname rather than keep a custom one.
+func resetNicknames(ctx context.Context, tx pgx.Tx, id ulid.ULID) error {
+	query := `UPDATE users_pokemons p
+				 SET nickname = COALESCE((SELECT s.name FROM pokemon_species s WHERE s.id = p.pokemon_id), '')
+			   WHERE p.user_id = $1`
+
+	_, err := tx.Exec(ctx, query, id)
+
+	return err
+}
+
+// scrubPokemonHistory strikes the user from the history of the Pokémon
+// they owned or traded away: nicknames they gave and their id in the
+// before and after snapshots. owner_id stays so the events keep counting,
+// it points at the anonymized row.
+func scrubPokemonHistory(ctx context.Context, tx pgx.Tx, id ulid.ULID) error {
+	query := `UPDATE users_pokemons_events
+				 SET before = CASE WHEN owner_id = $1 THEN before - 'nickname' ELSE before END
+							  - CASE WHEN before->>'user_id' = $2 THEN 'user_id' ELSE '' END,
+					 after = CASE WHEN owner_id = $1 THEN after - 'nickname' ELSE after END
+							 - CASE WHEN after->>'user_id' = $2 THEN 'user_id' ELSE '' END
+			   WHERE owner_id = $1 OR before->>'user_id' = $2 OR after->>'user_id' = $2`
+
+	_, err := tx.Exec(ctx, query, id, id.String())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users_pokemons_events SET actor_id = NULL WHERE actor_id = $1`, id)
+
+	return err
+}
+
+// purgeUser removes the user row for good. users_pokemons predates the
+// cascading foreign keys so its rows are removed explicitly; every other table
+// referencing users cascades on its own. Pokémon history outlives the
+// Pokémon's trainers, so the user is struck from it by hand.
+func purgeUser(ctx context.Context, tx pgx.Tx, id ulid.ULID) error {
+	_, err := tx.Exec(ctx, `DELETE FROM users_pokemons WHERE user_id = $1`, id)
+	if err != nil {
+		return err
+	}
 
-	_, err := tx.Exec(ctx, query, user.Id, user.Username, user.Password, user.Role, user.CreatedAt, user.UpdatedAt, user.DeletedAt)
+	_, err = tx.Exec(ctx, `DELETE FROM users_pokemons_events WHERE owner_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users_pokemons_events SET actor_id = NULL WHERE actor_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+
+	return err
+}
+
+// purgeUsersDeletedBefore skips anonymized accounts, they hold no personal
+// data anymore and are kept so their Pokémon still count in aggregate stats.
+func purgeUsersDeletedBefore(ctx context.Context, tx pgx.Tx, before time.Time) (int64, error) {
+	query := `DELETE FROM users_pokemons WHERE user_id IN (
+					SELECT id FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL
+			  )`
+
+	_, err := tx.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `DELETE FROM users_pokemons_events WHERE owner_id IN (
+					SELECT id FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL
+			  )`
+
+	_, err = tx.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `UPDATE users_pokemons_events SET actor_id = NULL WHERE actor_id IN (
+					SELECT id FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL
+			  )`
+
+	_, err = tx.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func savePasswordReset(ctx context.Context, tx pgx.Tx, reset PasswordReset) error {
+	query := `INSERT INTO users_password_resets (id, user_id, token_hash, created_at, expires_at, used_at)
+					VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (id) DO UPDATE SET
+					used_at = EXCLUDED.used_at;`
+
+	_, err := tx.Exec(ctx, query, reset.Id, reset.UserId, reset.TokenHash, reset.CreatedAt, reset.ExpiresAt, reset.UsedAt)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func findPasswordResetByTokenHash(ctx context.Context, tx pgx.Tx, tokenHash string) (PasswordReset, error) {
+	query := `SELECT id, user_id, token_hash, created_at, expires_at, used_at
+				FROM users_password_resets WHERE token_hash = $1
+			  FOR UPDATE`
+
+	row := tx.QueryRow(ctx, query, tokenHash)
+
+	var reset PasswordReset
+	if err := row.Scan(&reset.Id, &reset.UserId, &reset.TokenHash, &reset.CreatedAt, &reset.ExpiresAt, &reset.UsedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return PasswordReset{}, ErrorInvalidResetToken
+		}
+		return PasswordReset{}, err
+	}
+
+	return reset, nil
+}
+
+func deletePasswordResets(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	_, err := tx.Exec(ctx, `DELETE FROM users_password_resets WHERE user_id = $1`, userId)
+
+	return err
+}
+
+func invalidatePasswordResets(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	query := `UPDATE users_password_resets SET used_at = now()
+				WHERE user_id = $1 AND used_at IS NULL`
+
+	_, err := tx.Exec(ctx, query, userId)
+
+	return err
+}
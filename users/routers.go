@@ -1,7 +1,8 @@
 package users
 
 import (
-	"context"
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,24 +11,45 @@ import (
 	"github.com/oklog/ulid/v2"
 	"mda/helper"
 	"net/http"
+	"strconv"
 )
 
 func Router() *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Post("/login", loginHandler)
+	r.Post("/password/forgot", forgotPasswordHandler)
+	r.Post("/password/reset", resetPasswordHandler)
 
 	r.Group(func(r chi.Router) {
 		r.Use(helper.TokenAuth)
 		r.Get("/profile", getProfileHandler)
+		r.Get("/profile/export", exportProfileHandler)
+		r.Delete("/profile", deleteProfileHandler)
+		r.Get("/profile/privacy", getPrivacyHandler)
+		r.Put("/profile/privacy", updatePrivacyHandler)
+		r.Get("/friends", listFriendsHandler)
+		r.Delete("/friends/{id}", removeFriendHandler)
+		r.Get("/friends/requests", listFriendRequestsHandler)
+		r.Post("/friends/requests", sendFriendRequestHandler)
+		r.Post("/friends/requests/{id}/accept", respondFriendRequestHandler(true))
+		r.Post("/friends/requests/{id}/decline", respondFriendRequestHandler(false))
+		r.Get("/blocks", listBlocksHandler)
+		r.Post("/blocks", blockUserHandler)
+		r.Delete("/blocks/{id}", unblockUserHandler)
+		r.Get("/{id}/profile", getPublicProfileHandler)
 
 		r.Group(func(r chi.Router) {
 			r.Use(helper.RoleMiddleware(helper.RoleAdmin))
 			r.Get("/", listUsersHandler)
 			r.Post("/", createUserHandler)
+			r.Get("/deleted", listDeletedUsersHandler)
 			r.Get("/{id}", getUserHandler)
 			r.Put("/{id}", updateUserHandler)
 			r.Delete("/{id}", deleteUserHandler)
+			r.Put("/{id}/role", changeRoleHandler)
+			r.Put("/{id}/restore", restoreUserHandler)
+			r.Delete("/{id}/purge", purgeUserHandler)
 		})
 	})
 
@@ -55,7 +77,7 @@ func writeError(w http.ResponseWriter, status int, err error) {
 }
 
 func loginHandler(w http.ResponseWriter, req *http.Request) {
-	ctx := context.Background()
+	ctx := req.Context()
 
 	var j struct {
 		Username string `json:"username"`
@@ -96,10 +118,59 @@ func loginHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func parseUserFilter(req *http.Request) (UserFilter, error) {
+	q := req.URL.Query()
+
+	filter := UserFilter{
+		Role:  q.Get("role"),
+		Query: q.Get("q"),
+	}
+
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return UserFilter{}, fmt.Errorf("invalid limit: %s", s)
+		}
+		filter.Limit = limit
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		cursor, err := ulid.Parse(s)
+		if err != nil {
+			return UserFilter{}, fmt.Errorf("invalid cursor: %v", err)
+		}
+		filter.Cursor = cursor
+	}
+
+	switch q.Get("sort") {
+	case "", "created_at":
+	case "-created_at":
+		filter.Descending = true
+	default:
+		return UserFilter{}, fmt.Errorf("unsupported sort: %s", q.Get("sort"))
+	}
+
+	if s := q.Get("include_deleted"); s != "" {
+		includeDeleted, err := strconv.ParseBool(s)
+		if err != nil {
+			return UserFilter{}, fmt.Errorf("invalid include_deleted: %s", s)
+		}
+		filter.IncludeDeleted = includeDeleted
+	}
+
+	return filter, nil
+}
+
 func listUsersHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
-	users, err := listUsers(ctx)
+	filter, err := parseUserFilter(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	users, err := listUsers(ctx, filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
@@ -157,7 +228,7 @@ func getProfileHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, err := findUser(ctx, currentUserIdUlid)
+	profile, err := findProfile(ctx, currentUserIdUlid)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, fmt.Errorf("error fetching user: %v", err))
 		return
@@ -166,7 +237,7 @@ func getProfileHandler(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("content-type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	err = json.NewEncoder(w).Encode(user)
+	err = json.NewEncoder(w).Encode(profile)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
@@ -179,6 +250,7 @@ func createUserHandler(w http.ResponseWriter, req *http.Request) {
 	var j struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		Email    string `json:"email"`
 	}
 
 	err := json.NewDecoder(req.Body).Decode(&j)
@@ -187,7 +259,7 @@ func createUserHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, err := createUser(ctx, j.Username, j.Password)
+	user, err := createUser(ctx, j.Username, j.Password, j.Email)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
@@ -215,6 +287,7 @@ func updateUserHandler(w http.ResponseWriter, req *http.Request) {
 	var j struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		Email    string `json:"email"`
 	}
 
 	err = json.NewDecoder(req.Body).Decode(&j)
@@ -223,7 +296,7 @@ func updateUserHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, err := updateUser(ctx, id, j.Username, j.Password)
+	user, err := updateUser(ctx, id, j.Username, j.Password, j.Email)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
@@ -257,3 +330,280 @@ func deleteUserHandler(w http.ResponseWriter, req *http.Request) {
 
 	writeMessage(w, http.StatusOK, "user deleted")
 }
+
+func forgotPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		Email string `json:"email"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if j.Email == "" {
+		writeError(w, http.StatusBadRequest, errors.New("email is required"))
+		return
+	}
+
+	err = requestPasswordReset(ctx, j.Email)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeMessage(w, http.StatusAccepted, "if the email belongs to an account, a reset token has been sent")
+}
+
+func resetPasswordHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = resetPassword(ctx, j.Token, j.Password)
+	if errors.Is(err, ErrorInvalidResetToken) || errors.Is(err, ErrorEmptyPassword) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "password has been reset")
+}
+
+func listDeletedUsersHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	users, err := listDeletedUsers(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(users)
+	if err != nil {
+		return
+	}
+}
+
+func restoreUserHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	userId := chi.URLParam(req, "id")
+
+	id, err := ulid.Parse(userId)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := restoreUser(ctx, id)
+	if errors.Is(err, ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrorUserAnonymized) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(user)
+	if err != nil {
+		return
+	}
+}
+
+func purgeUserHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	userId := chi.URLParam(req, "id")
+
+	id, err := ulid.Parse(userId)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = purgeDeletedUser(ctx, id)
+	if errors.Is(err, ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "user purged")
+}
+
+func exportProfileHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	currentUserId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported format: %s", format))
+		return
+	}
+
+	export, err := exportUser(ctx, currentUserId)
+	if errors.Is(err, ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if format == "zip" {
+		writeExportArchive(w, export)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.Header().Add("content-disposition", `attachment; filename="export.json"`)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(export)
+	if err != nil {
+		return
+	}
+}
+
+func writeExportArchive(w http.ResponseWriter, export UserExport) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, part := range export.parts() {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     part.Name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(part.Value); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Add("content-type", "application/zip")
+	w.Header().Add("content-disposition", `attachment; filename="export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(buf.Bytes())
+}
+
+func deleteProfileHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	currentUserId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = anonymizeUser(ctx, currentUserId)
+	if errors.Is(err, ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "account deleted and personal data erased")
+}
+
+func changeRoleHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	userId := chi.URLParam(req, "id")
+
+	id, err := ulid.Parse(userId)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		Role string `json:"role"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := changeUserRole(ctx, id, j.Role)
+	if errors.Is(err, ErrorInvalidRole) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if errors.Is(err, ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
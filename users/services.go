@@ -3,7 +3,15 @@ package users
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"mda/achievements"
+	"mda/audit"
+	"mda/helper"
+	"mda/mailer"
+	"time"
 )
 
 func authenticate(ctx context.Context, username, password string) (User, error) {
@@ -14,9 +22,45 @@ func authenticate(ctx context.Context, username, password string) (User, error)
 	defer tx.Rollback(ctx)
 
 	user, err := findUserByUsernameAndPassword(ctx, tx, username, password)
+	if errors.Is(err, ErrorUserNotFound) {
+		// What was typed is never kept, it is too often a password. The
+		// event names the account only when one goes by that username.
+		targetId := ""
+		id, err := findUserIdByUsername(ctx, tx, username)
+		if err == nil {
+			targetId = id.String()
+		} else if !errors.Is(err, ErrorUserNotFound) {
+			return User{}, err
+		}
+
+		event, err := audit.NewEvent(ctx, audit.ActionLoginFailure, audit.TargetUser, targetId)
+		if err != nil {
+			return User{}, err
+		}
+
+		if err := audit.Record(ctx, tx, event); err != nil {
+			return User{}, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return User{}, err
+		}
+
+		return User{}, ErrorUserNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	event, err := audit.NewEvent(ctx, audit.ActionLoginSuccess, audit.TargetUser, user.Id.String())
 	if err != nil {
 		return User{}, err
 	}
+	event.ActorId = &user.Id
+
+	if err := audit.Record(ctx, tx, event); err != nil {
+		return User{}, err
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return User{}, err
@@ -25,13 +69,17 @@ func authenticate(ctx context.Context, username, password string) (User, error)
 	return user, nil
 }
 
-func listUsers(ctx context.Context) (UserList, error) {
-	tx, err := pool.Begin(ctx)
+func listUsers(ctx context.Context, filter UserFilter) (UserList, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
 	if err != nil {
 		return UserList{}, err
 	}
+	defer tx.Rollback(ctx)
 
-	list, err := findAllUsers(ctx, tx)
+	list, err := findAllUsers(ctx, tx, filter)
 	if err != nil {
 		return UserList{}, err
 	}
@@ -61,8 +109,32 @@ func findUser(ctx context.Context, id ulid.ULID) (User, error) {
 	return user, nil
 }
 
-func createUser(ctx context.Context, username, password string) (user User, err error) {
-	userItem, err := NewUser(username, password)
+func findProfile(ctx context.Context, id ulid.ULID) (Profile, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findUserById(ctx, tx, id)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	badges, err := achievements.FindBadges(ctx, tx, id)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Profile{}, err
+	}
+
+	return Profile{User: user, Badges: badges}, nil
+}
+
+func createUser(ctx context.Context, username, password, email string) (user User, err error) {
+	userItem, err := NewUser(username, password, email)
 	if err != nil {
 		return
 	}
@@ -78,16 +150,26 @@ func createUser(ctx context.Context, username, password string) (user User, err
 		return
 	}
 
+	err = recordEvent(ctx, tx, audit.ActionUserCreate, userItem.Id, map[string]interface{}{
+		"username": userItem.Username,
+		"role":     userItem.Role,
+	})
+	if err != nil {
+		tx.Rollback(ctx)
+		return
+	}
+
 	tx.Commit(ctx)
 
 	return userItem, nil
 }
 
 func CreateAdminUser(ctx context.Context, username, password string) (user User, err error) {
-	userItem, err := NewUser(username, password)
+	userItem, err := NewUser(username, password, "")
 	if err != nil {
 		return
 	}
+	userItem.Role = helper.RoleAdmin
 
 	tx, err := pool.Begin(ctx)
 	if err != nil {
@@ -114,7 +196,7 @@ func CreateAdminUser(ctx context.Context, username, password string) (user User,
 	return userItem, nil
 }
 
-func updateUser(ctx context.Context, id ulid.ULID, username, password string) (user User, err error) {
+func updateUser(ctx context.Context, id ulid.ULID, username, password, email string) (user User, err error) {
 	tx, err := pool.Begin(ctx)
 	if err != nil {
 		return
@@ -126,7 +208,9 @@ func updateUser(ctx context.Context, id ulid.ULID, username, password string) (u
 		return User{}, err
 	}
 
-	err = UpdateUser(&user, username, password)
+	before := user
+
+	err = UpdateUser(&user, username, password, email)
 	if err != nil {
 		tx.Rollback(ctx)
 		return User{}, err
@@ -138,6 +222,16 @@ func updateUser(ctx context.Context, id ulid.ULID, username, password string) (u
 		return User{}, err
 	}
 
+	err = recordEvent(ctx, tx, audit.ActionUserUpdate, user.Id, map[string]interface{}{
+		"username_changed": before.Username != user.Username,
+		"password_changed": before.Password != user.Password,
+		"email_changed":    before.Email != user.Email,
+	})
+	if err != nil {
+		tx.Rollback(ctx)
+		return User{}, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return User{}, err
 	}
@@ -165,7 +259,392 @@ func deleteUser(ctx context.Context, id ulid.ULID) error {
 		return err
 	}
 
+	err = recordEvent(ctx, tx, audit.ActionUserDelete, user.Id, nil)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
 	tx.Commit(ctx)
 
 	return nil
 }
+
+// requestPasswordReset never tells the caller whether the email belongs to an
+// account, so the endpoint cannot be used to enumerate users. The mail goes
+// out in the background, so the response takes no longer for an account
+// than for an unknown address.
+func requestPasswordReset(ctx context.Context, email string) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findUserByEmail(ctx, tx, email)
+	if errors.Is(err, ErrorUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = invalidatePasswordResets(ctx, tx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	reset, token, err := NewPasswordReset(user.Id, resetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	err = savePasswordReset(ctx, tx, reset)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email.String,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse this token to reset your password: %s\n\nIt expires at %s. If you did not ask for a reset you can ignore this email.\n",
+			user.Username, token, reset.ExpiresAt.Format("2006-01-02 15:04 MST"),
+		),
+	}
+
+	go sendPasswordResetMail(user.Id, msg)
+
+	return nil
+}
+
+// sendPasswordResetMail outlives the request, so it does not use its
+// context; the mailer bounds the delivery itself.
+func sendPasswordResetMail(userId ulid.ULID, msg mailer.Message) {
+	if err := mail.Send(context.Background(), msg); err != nil {
+		log.Error().Err(err).Str("user_id", userId.String()).Msg("cannot send password reset mail")
+	}
+}
+
+func resetPassword(ctx context.Context, token, password string) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	reset, err := findPasswordResetByTokenHash(ctx, tx, hashResetToken(token))
+	if err != nil {
+		return err
+	}
+
+	err = UsePasswordReset(&reset)
+	if err != nil {
+		return err
+	}
+
+	user, err := findUserById(ctx, tx, reset.UserId)
+	if errors.Is(err, ErrorUserNotFound) {
+		return ErrorInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = ChangePassword(&user, password)
+	if err != nil {
+		return err
+	}
+
+	err = saveUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	err = savePasswordReset(ctx, tx, reset)
+	if err != nil {
+		return err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionUserPasswordReset, user.Id, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func listDeletedUsers(ctx context.Context) (UserList, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return UserList{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	list, err := findDeletedUsers(ctx, tx)
+	if err != nil {
+		return UserList{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UserList{}, err
+	}
+
+	return list, nil
+}
+
+func restoreUser(ctx context.Context, id ulid.ULID) (User, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findDeletedUserById(ctx, tx, id)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = RestoreUser(&user)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = saveUser(ctx, tx, user)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionUserRestore, user.Id, nil)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func purgeDeletedUser(ctx context.Context, id ulid.ULID) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findDeletedUserById(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = purgeUser(ctx, tx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionUserPurge, user.Id, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func purgeExpiredUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	count, err := purgeUsersDeletedBefore(ctx, tx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func exportUser(ctx context.Context, id ulid.ULID) (UserExport, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return UserExport{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findUserById(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	pokemons, err := findExportedPokemons(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	pokemonEvents, err := findExportedPokemonEvents(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	badges, err := achievements.FindBadges(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	social, err := findExportedSocial(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	resets, err := findExportedPasswordResets(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	events, err := audit.FindByUser(ctx, tx, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UserExport{}, err
+	}
+
+	return UserExport{
+		ExportedAt:     time.Now(),
+		Account:        newExportedAccount(user),
+		Pokemons:       pokemons,
+		PokemonEvents:  pokemonEvents,
+		Badges:         badges,
+		Social:         social,
+		PasswordResets: resets,
+		AuditEvents:    events,
+	}, nil
+}
+
+func anonymizeUser(ctx context.Context, id ulid.ULID) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findUserById(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = AnonymizeUser(&user)
+	if err != nil {
+		return err
+	}
+
+	err = saveUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	err = deletePasswordResets(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = deleteSocialGraph(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = clearPokemonNotes(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = resetNicknames(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = scrubPokemonHistory(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionUserAnonymize, id, nil)
+	if err != nil {
+		return err
+	}
+
+	// Last, so the IP on the anonymize event itself goes too.
+	err = audit.ScrubUser(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func changeUserRole(ctx context.Context, id ulid.ULID, role string) (User, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	user, err := findUserById(ctx, tx, id)
+	if err != nil {
+		return User{}, err
+	}
+
+	previous := user.Role
+
+	err = ChangeRole(&user, role)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = saveUser(ctx, tx, user)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionUserRoleChange, user.Id, map[string]interface{}{
+		"from": previous,
+		"to":   user.Role,
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func recordEvent(ctx context.Context, tx pgx.Tx, action string, target ulid.ULID, metadata map[string]interface{}) error {
+	event, err := audit.NewEvent(ctx, action, audit.TargetUser, target.String())
+	if err != nil {
+		return err
+	}
+
+	for k, v := range metadata {
+		event.Metadata[k] = v
+	}
+
+	return audit.Record(ctx, tx, event)
+}
@@ -0,0 +1,275 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+// lockFriendshipPair serialises everything touching the link between two
+// users, whichever way round they are given. findFriendship cannot do it
+// alone: when no row exists yet there is nothing to lock, and two requests
+// crossing each other would both go on to insert one.
+func lockFriendshipPair(ctx context.Context, tx pgx.Tx, a, b ulid.ULID) error {
+	if b.Compare(a) < 0 {
+		a, b = b, a
+	}
+
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, "friendship:"+a.String()+":"+b.String())
+
+	return err
+}
+
+// findFriendship finds the link between two users whichever of them sent
+// the request, and locks it for the rest of tx.
+func findFriendship(ctx context.Context, tx pgx.Tx, a, b ulid.ULID) (Friendship, error) {
+	query := `SELECT user_id, friend_id, status, created_at, accepted_at
+				FROM users_friendships
+			 WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
+			 FOR UPDATE`
+
+	var f Friendship
+	err := tx.QueryRow(ctx, query, a, b).Scan(&f.UserId, &f.FriendId, &f.Status, &f.CreatedAt, &f.AcceptedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Friendship{}, ErrorFriendRequestNotFound
+		}
+		return Friendship{}, err
+	}
+
+	return f, nil
+}
+
+func saveFriendship(ctx context.Context, tx pgx.Tx, f Friendship) error {
+	query := `INSERT INTO users_friendships (user_id, friend_id, status, created_at, accepted_at)
+				VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (user_id, friend_id) DO UPDATE SET
+				status = EXCLUDED.status,
+				accepted_at = EXCLUDED.accepted_at`
+
+	_, err := tx.Exec(ctx, query, f.UserId, f.FriendId, f.Status, f.CreatedAt, f.AcceptedAt)
+
+	// The pair index catches a link the other way round that slipped past
+	// lockFriendshipPair.
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "users_friendships_pair_idx" {
+		return ErrorFriendRequestExists
+	}
+
+	return err
+}
+
+func deleteFriendship(ctx context.Context, tx pgx.Tx, a, b ulid.ULID) (bool, error) {
+	query := `DELETE FROM users_friendships
+			 WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)`
+
+	tag, err := tx.Exec(ctx, query, a, b)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func findFriends(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Friend, error) {
+	query := `SELECT u.id, u.username, f.accepted_at
+				FROM users_friendships f
+				JOIN users u ON u.id = CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END
+			 WHERE (f.user_id = $1 OR f.friend_id = $1)
+			   AND f.status = 'accepted'
+			   AND u.deleted_at IS NULL
+			 ORDER BY u.username`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friends := []Friend{}
+	for rows.Next() {
+		var f Friend
+		if err := rows.Scan(&f.Id, &f.Username, &f.Since); err != nil {
+			return nil, err
+		}
+
+		friends = append(friends, f)
+	}
+
+	return friends, rows.Err()
+}
+
+func findFriendRequests(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]FriendRequest, error) {
+	query := `SELECT u.id, u.username,
+					 CASE WHEN f.user_id = $1 THEN 'outgoing' ELSE 'incoming' END,
+					 f.created_at
+				FROM users_friendships f
+				JOIN users u ON u.id = CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END
+			 WHERE (f.user_id = $1 OR f.friend_id = $1)
+			   AND f.status = 'pending'
+			   AND u.deleted_at IS NULL
+			 ORDER BY f.created_at`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []FriendRequest{}
+	for rows.Next() {
+		var r FriendRequest
+		if err := rows.Scan(&r.UserId, &r.Username, &r.Direction, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, r)
+	}
+
+	return requests, rows.Err()
+}
+
+// FriendIds lists the users userId has accepted friendships with.
+func FriendIds(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]ulid.ULID, error) {
+	query := `SELECT CASE WHEN user_id = $1 THEN friend_id ELSE user_id END
+				FROM users_friendships
+			 WHERE (user_id = $1 OR friend_id = $1) AND status = 'accepted'`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []ulid.ULID{}
+	for rows.Next() {
+		var id ulid.ULID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func AreFriends(ctx context.Context, tx pgx.Tx, a, b ulid.ULID) (bool, error) {
+	query := `SELECT EXISTS(
+				SELECT 1 FROM users_friendships
+				 WHERE ((user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1))
+				   AND status = 'accepted'
+			  )`
+
+	var friends bool
+	err := tx.QueryRow(ctx, query, a, b).Scan(&friends)
+
+	return friends, err
+}
+
+// hasBlocked reports whether userId blocked otherId. Blocks are one way;
+// callers that care about either direction ask twice.
+func hasBlocked(ctx context.Context, tx pgx.Tx, userId, otherId ulid.ULID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users_blocks WHERE user_id = $1 AND blocked_id = $2)`
+
+	var blocked bool
+	err := tx.QueryRow(ctx, query, userId, otherId).Scan(&blocked)
+
+	return blocked, err
+}
+
+func saveBlock(ctx context.Context, tx pgx.Tx, userId, blockedId ulid.ULID, at time.Time) error {
+	query := `INSERT INTO users_blocks (user_id, blocked_id, created_at)
+				VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING`
+
+	_, err := tx.Exec(ctx, query, userId, blockedId, at)
+
+	return err
+}
+
+func deleteBlock(ctx context.Context, tx pgx.Tx, userId, blockedId ulid.ULID) (bool, error) {
+	tag, err := tx.Exec(ctx, `DELETE FROM users_blocks WHERE user_id = $1 AND blocked_id = $2`, userId, blockedId)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func findBlocks(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]BlockedUser, error) {
+	query := `SELECT u.id, u.username, b.created_at
+				FROM users_blocks b
+				JOIN users u ON u.id = b.blocked_id
+			 WHERE b.user_id = $1
+			 ORDER BY b.created_at`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []BlockedUser{}
+	for rows.Next() {
+		var b BlockedUser
+		if err := rows.Scan(&b.Id, &b.Username, &b.BlockedAt); err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	return blocks, rows.Err()
+}
+
+// findPrivacy falls back to DefaultPrivacy for users who never saved any
+// settings.
+func findPrivacy(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (Privacy, error) {
+	query := `SELECT user_id, profile, collection, pokedex, trades, battles, updated_at
+				FROM users_privacy
+			 WHERE user_id = $1`
+
+	var p Privacy
+	err := tx.QueryRow(ctx, query, userId).Scan(&p.UserId, &p.Profile, &p.Collection, &p.Pokedex, &p.Trades, &p.Battles, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultPrivacy(userId), nil
+		}
+		return Privacy{}, err
+	}
+
+	return p, nil
+}
+
+func savePrivacy(ctx context.Context, tx pgx.Tx, p Privacy) error {
+	query := `INSERT INTO users_privacy (user_id, profile, collection, pokedex, trades, battles, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT (user_id) DO UPDATE SET
+				profile = EXCLUDED.profile,
+				collection = EXCLUDED.collection,
+				pokedex = EXCLUDED.pokedex,
+				trades = EXCLUDED.trades,
+				battles = EXCLUDED.battles,
+				updated_at = EXCLUDED.updated_at`
+
+	_, err := tx.Exec(ctx, query, p.UserId, p.Profile, p.Collection, p.Pokedex, p.Trades, p.Battles, p.UpdatedAt)
+
+	return err
+}
+
+// deleteSocialGraph drops every friendship, request and block the user is
+// part of, in either direction.
+func deleteSocialGraph(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	_, err := tx.Exec(ctx, `DELETE FROM users_friendships WHERE user_id = $1 OR friend_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM users_blocks WHERE user_id = $1 OR blocked_id = $1`, userId)
+
+	return err
+}
@@ -0,0 +1,296 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"net/http"
+)
+
+func writeSocialError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorUserNotFound), errors.Is(err, ErrorFriendRequestNotFound), errors.Is(err, ErrorFriendNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrorUserBlocked), errors.Is(err, ErrorNotAllowed):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, ErrorAlreadyFriends), errors.Is(err, ErrorFriendRequestExists):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrorFriendSelf), errors.Is(err, ErrorBlockSelf), errors.Is(err, ErrorInvalidAspect), errors.Is(err, ErrorInvalidAudience):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// decodeUserId reads a {"user_id": ...} request body.
+func decodeUserId(req *http.Request) (ulid.ULID, error) {
+	var j struct {
+		UserId ulid.ULID `json:"user_id"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+
+	return j.UserId, err
+}
+
+func listFriendsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	friends, err := listFriends(ctx, userId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, friends)
+}
+
+func removeFriendHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	friendId, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = removeFriend(ctx, userId, friendId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "friend removed")
+}
+
+func listFriendRequestsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	requests, err := listFriendRequests(ctx, userId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requests)
+}
+
+func sendFriendRequestHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	toId, err := decodeUserId(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	friendship, err := sendFriendRequest(ctx, userId, toId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, friendship)
+}
+
+func respondFriendRequestHandler(accept bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		requesterId, err := ulid.Parse(chi.URLParam(req, "id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		userId, err := helper.CurrentUserId(ctx)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		friendship, err := respondFriendRequest(ctx, userId, requesterId, accept)
+		if err != nil {
+			writeSocialError(w, err)
+			return
+		}
+
+		if !accept {
+			writeMessage(w, http.StatusOK, "friend request declined")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, friendship)
+	}
+}
+
+func listBlocksHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	blocks, err := listBlocks(ctx, userId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+func blockUserHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	blockedId, err := decodeUserId(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = blockUser(ctx, userId, blockedId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "user blocked")
+}
+
+func unblockUserHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	blockedId, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = unblockUser(ctx, userId, blockedId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "user unblocked")
+}
+
+func getPrivacyHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	privacy, err := getPrivacy(ctx, userId)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, privacy)
+}
+
+// updatePrivacyHandler takes only the aspects to change, for example
+// {"collection": "everyone"}.
+func updatePrivacyHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var changes map[string]string
+	err := json.NewDecoder(req.Body).Decode(&changes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	privacy, err := updatePrivacy(ctx, userId, changes)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, privacy)
+}
+
+func getPublicProfileHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	profile, err := getPublicProfile(ctx, userId, id)
+	if err != nil {
+		writeSocialError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, profile)
+}
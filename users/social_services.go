@@ -0,0 +1,421 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"mda/achievements"
+	"mda/realtime"
+	"time"
+)
+
+// CheckAccess decides whether viewerId may see or act on one aspect of
+// ownerId. A block in either direction shuts everything; otherwise the
+// owner's privacy setting for the aspect decides.
+func CheckAccess(ctx context.Context, tx pgx.Tx, viewerId, ownerId ulid.ULID, aspect string) error {
+	if viewerId == ownerId {
+		return nil
+	}
+
+	if _, err := findUserById(ctx, tx, ownerId); err != nil {
+		return err
+	}
+
+	blocked, err := IsBlocked(ctx, tx, viewerId, ownerId)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return ErrorUserBlocked
+	}
+
+	privacy, err := findPrivacy(ctx, tx, ownerId)
+	if err != nil {
+		return err
+	}
+
+	switch privacy.Audience(aspect) {
+	case AudienceEveryone:
+		return nil
+	case AudienceFriends:
+		friends, err := AreFriends(ctx, tx, viewerId, ownerId)
+		if err != nil {
+			return err
+		}
+
+		if friends {
+			return nil
+		}
+	}
+
+	return ErrorNotAllowed
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func IsBlocked(ctx context.Context, tx pgx.Tx, a, b ulid.ULID) (bool, error) {
+	blocked, err := hasBlocked(ctx, tx, a, b)
+	if err != nil || blocked {
+		return blocked, err
+	}
+
+	return hasBlocked(ctx, tx, b, a)
+}
+
+// sendFriendRequest asks toId to be friends. A request crossing one that
+// toId already sent accepts it instead, even when both are sent at once:
+// the pair lock makes the later one wait and see the earlier.
+func sendFriendRequest(ctx context.Context, fromId, toId ulid.ULID) (Friendship, error) {
+	friendship, err := NewFriendRequest(fromId, toId)
+	if err != nil {
+		return Friendship{}, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Friendship{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := findUserById(ctx, tx, toId); err != nil {
+		return Friendship{}, err
+	}
+
+	blocked, err := IsBlocked(ctx, tx, fromId, toId)
+	if err != nil {
+		return Friendship{}, err
+	}
+
+	if blocked {
+		return Friendship{}, ErrorUserBlocked
+	}
+
+	err = lockFriendshipPair(ctx, tx, fromId, toId)
+	if err != nil {
+		return Friendship{}, err
+	}
+
+	eventType := realtime.EventFriendRequested
+
+	existing, err := findFriendship(ctx, tx, fromId, toId)
+	switch {
+	case err == nil && existing.Status == FriendAccepted:
+		return Friendship{}, ErrorAlreadyFriends
+	case err == nil && existing.UserId == fromId:
+		return Friendship{}, ErrorFriendRequestExists
+	case err == nil:
+		friendship = existing
+		if err := AcceptFriendRequest(&friendship, fromId); err != nil {
+			return Friendship{}, err
+		}
+		eventType = realtime.EventFriendAccepted
+	case !errors.Is(err, ErrorFriendRequestNotFound):
+		return Friendship{}, err
+	}
+
+	err = saveFriendship(ctx, tx, friendship)
+	if err != nil {
+		return Friendship{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Friendship{}, err
+	}
+
+	notifyFriend(eventType, toId, friendship)
+
+	return friendship, nil
+}
+
+// respondFriendRequest accepts or declines the request requesterId sent to
+// userId. A declined request is dropped so it can be sent again later.
+func respondFriendRequest(ctx context.Context, userId, requesterId ulid.ULID, accept bool) (Friendship, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Friendship{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	friendship, err := findFriendship(ctx, tx, userId, requesterId)
+	if err != nil {
+		return Friendship{}, err
+	}
+
+	if friendship.Status != FriendPending || friendship.FriendId != userId {
+		return Friendship{}, ErrorFriendRequestNotFound
+	}
+
+	if accept {
+		if err := AcceptFriendRequest(&friendship, userId); err != nil {
+			return Friendship{}, err
+		}
+
+		err = saveFriendship(ctx, tx, friendship)
+	} else {
+		_, err = deleteFriendship(ctx, tx, userId, requesterId)
+	}
+
+	if err != nil {
+		return Friendship{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Friendship{}, err
+	}
+
+	if accept {
+		notifyFriend(realtime.EventFriendAccepted, requesterId, friendship)
+	}
+
+	return friendship, nil
+}
+
+// removeFriend ends a friendship, or withdraws a request either side sent.
+func removeFriend(ctx context.Context, userId, friendId ulid.ULID) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	removed, err := deleteFriendship(ctx, tx, userId, friendId)
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return ErrorFriendNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+func listFriends(ctx context.Context, userId ulid.ULID) ([]Friend, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	friends, err := findFriends(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return friends, nil
+}
+
+func listFriendRequests(ctx context.Context, userId ulid.ULID) ([]FriendRequest, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	requests, err := findFriendRequests(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// blockUser also ends any friendship or pending request between the two.
+func blockUser(ctx context.Context, userId, blockedId ulid.ULID) error {
+	if userId == blockedId {
+		return ErrorBlockSelf
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := findUserById(ctx, tx, blockedId); err != nil {
+		return err
+	}
+
+	_, err = deleteFriendship(ctx, tx, userId, blockedId)
+	if err != nil {
+		return err
+	}
+
+	err = saveBlock(ctx, tx, userId, blockedId, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func unblockUser(ctx context.Context, userId, blockedId ulid.ULID) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	removed, err := deleteBlock(ctx, tx, userId, blockedId)
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return ErrorUserNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+func listBlocks(ctx context.Context, userId ulid.ULID) ([]BlockedUser, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	blocks, err := findBlocks(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+func getPrivacy(ctx context.Context, userId ulid.ULID) (Privacy, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Privacy{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	privacy, err := findPrivacy(ctx, tx, userId)
+	if err != nil {
+		return Privacy{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Privacy{}, err
+	}
+
+	return privacy, nil
+}
+
+func updatePrivacy(ctx context.Context, userId ulid.ULID, changes map[string]string) (Privacy, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Privacy{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	privacy, err := findPrivacy(ctx, tx, userId)
+	if err != nil {
+		return Privacy{}, err
+	}
+
+	err = UpdatePrivacy(&privacy, changes)
+	if err != nil {
+		return Privacy{}, err
+	}
+
+	err = savePrivacy(ctx, tx, privacy)
+	if err != nil {
+		return Privacy{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Privacy{}, err
+	}
+
+	return privacy, nil
+}
+
+func getPublicProfile(ctx context.Context, viewerId, id ulid.ULID) (PublicProfile, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return PublicProfile{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = CheckAccess(ctx, tx, viewerId, id, AspectProfile)
+	if err != nil {
+		return PublicProfile{}, err
+	}
+
+	user, err := findUserById(ctx, tx, id)
+	if err != nil {
+		return PublicProfile{}, err
+	}
+
+	friends, err := AreFriends(ctx, tx, viewerId, id)
+	if err != nil {
+		return PublicProfile{}, err
+	}
+
+	badges, err := achievements.FindBadges(ctx, tx, id)
+	if err != nil {
+		return PublicProfile{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return PublicProfile{}, err
+	}
+
+	return PublicProfile{
+		Id:        user.Id,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+		IsFriend:  friends,
+		Badges:    badges,
+	}, nil
+}
+
+func findExportedSocial(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (ExportedSocial, error) {
+	friends, err := findFriends(ctx, tx, userId)
+	if err != nil {
+		return ExportedSocial{}, err
+	}
+
+	requests, err := findFriendRequests(ctx, tx, userId)
+	if err != nil {
+		return ExportedSocial{}, err
+	}
+
+	blocks, err := findBlocks(ctx, tx, userId)
+	if err != nil {
+		return ExportedSocial{}, err
+	}
+
+	privacy, err := findPrivacy(ctx, tx, userId)
+	if err != nil {
+		return ExportedSocial{}, err
+	}
+
+	return ExportedSocial{
+		Friends:  friends,
+		Requests: requests,
+		Blocks:   blocks,
+		Privacy:  privacy,
+	}, nil
+}
+
+func notifyFriend(eventType string, userId ulid.ULID, friendship Friendship) {
+	if err := realtime.Publish(userId, eventType, friendship); err != nil {
+		log.Error().Err(err).Msg("failed to publish friend event")
+	}
+}
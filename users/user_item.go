@@ -1,8 +1,12 @@
 package users
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/oklog/ulid/v2"
 	"gopkg.in/guregu/null.v4"
+	"mda/helper"
+	"strings"
 	"time"
 )
 
@@ -10,13 +14,16 @@ type User struct {
 	Id        ulid.ULID
 	Username  string
 	Password  string
+	Email     null.String
 	Role      string
 	CreatedAt time.Time
 	UpdatedAt null.Time
 	DeletedAt null.Time
+
+	AnonymizedAt null.Time
 }
 
-func NewUser(username, password string) (User, error) {
+func NewUser(username, password, email string) (User, error) {
 	id, err := ulid.New(ulid.Timestamp(time.Now()), nil)
 	if err != nil {
 		return User{}, err
@@ -26,19 +33,84 @@ func NewUser(username, password string) (User, error) {
 		Id:        id,
 		Username:  username,
 		Password:  password,
+		Email:     null.NewString(email, email != ""),
 		Role:      "user",
 		CreatedAt: time.Now(),
 	}, nil
 }
 
-func UpdateUser(user *User, username, password string) error {
+func UpdateUser(user *User, username, password, email string) error {
 	user.Username = username
+	user.Password = password
+	if email != "" {
+		user.Email = null.StringFrom(email)
+	}
+	user.UpdatedAt = null.TimeFrom(time.Now())
+
+	return nil
+}
+
+func ChangePassword(user *User, password string) error {
+	if password == "" {
+		return ErrorEmptyPassword
+	}
+
 	user.Password = password
 	user.UpdatedAt = null.TimeFrom(time.Now())
 
 	return nil
 }
 
+func ChangeRole(user *User, role string) error {
+	if role != helper.RoleAdmin && role != helper.RoleUser {
+		return ErrorInvalidRole
+	}
+
+	user.Role = role
+	user.UpdatedAt = null.TimeFrom(time.Now())
+
+	return nil
+}
+
 func DeleteUser(user *User) {
 	user.DeletedAt = null.TimeFrom(time.Now())
 }
+
+func RestoreUser(user *User) error {
+	if !user.DeletedAt.Valid {
+		return ErrorUserNotDeleted
+	}
+
+	if user.AnonymizedAt.Valid {
+		return ErrorUserAnonymized
+	}
+
+	user.DeletedAt = null.Time{}
+	user.UpdatedAt = null.TimeFrom(time.Now())
+
+	return nil
+}
+
+// AnonymizeUser strips everything that identifies the person behind the
+// account. The row itself stays, so owned Pokémon keep counting in stats.
+func AnonymizeUser(user *User) error {
+	if user.AnonymizedAt.Valid {
+		return ErrorUserAnonymized
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	user.Username = "deleted-" + strings.ToLower(user.Id.String())
+	user.Password = hex.EncodeToString(secret)
+	user.Email = null.String{}
+	user.UpdatedAt = null.TimeFrom(now)
+	user.DeletedAt = null.TimeFrom(now)
+	user.AnonymizedAt = null.TimeFrom(now)
+
+	return nil
+}
@@ -12,19 +12,24 @@ func (u User) MarshalJSON() ([]byte, error) {
 		Id        ulid.ULID  `json:"id"`
 		Username  string     `json:"username"`
 		Password  string     `json:"password"`
+		Email     *string    `json:"email,omitempty"`
 		Role      string     `json:"role"`
 		CreatedAt time.Time  `json:"created_at"`
 		UpdatedAt *time.Time `json:"updated_at,omitempty"`
 		DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+		AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
 	}
 
 	j.Id = u.Id
 	j.Username = u.Username
 	j.Password = u.Password
+	j.Email = u.Email.Ptr()
 	j.Role = u.Role
 	j.CreatedAt = u.CreatedAt
 	j.UpdatedAt = u.UpdatedAt.Ptr()
 	j.DeletedAt = u.DeletedAt.Ptr()
+	j.AnonymizedAt = u.AnonymizedAt.Ptr()
 
 	return json.Marshal(j)
 }
@@ -48,10 +53,13 @@ func (u *User) UnmarshalJSON(data []byte) error {
 		Id        ulid.ULID   `json:"id"`
 		Username  string      `json:"username"`
 		Password  string      `json:"password"`
+		Email     null.String `json:"email"`
 		Role      string      `json:"role"`
 		CreatedAt string      `json:"created_at"`
 		UpdatedAt null.String `json:"updated_at"`
 		DeletedAt null.String `json:"deleted_at"`
+
+		AnonymizedAt null.String `json:"anonymized_at"`
 	}
 
 	err := json.Unmarshal(data, &j)
@@ -67,15 +75,19 @@ func (u *User) UnmarshalJSON(data []byte) error {
 
 	UpdatedAt := parseNullStringToNullTime(j.UpdatedAt)
 	DeletedAt := parseNullStringToNullTime(j.DeletedAt)
+	AnonymizedAt := parseNullStringToNullTime(j.AnonymizedAt)
 
 	u = &User{
 		Id:        j.Id,
 		Username:  j.Username,
 		Password:  j.Password,
+		Email:     j.Email,
 		Role:      j.Role,
 		CreatedAt: CreatedAt,
 		UpdatedAt: UpdatedAt,
 		DeletedAt: DeletedAt,
+
+		AnonymizedAt: AnonymizedAt,
 	}
 
 	return nil
@@ -0,0 +1,116 @@
+package userspokemon
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTags         = 10
+	MaxTagLength    = 30
+	MaxNoteLength   = 500
+	maxBulkReleases = 100
+)
+
+// NormaliseTags lowercases and trims tags, drops empty ones and
+// duplicates, and sorts what is left. Tags may hold letters, digits, - and _.
+func NormaliseTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalised := []string{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, ErrTagTooLong
+		}
+
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+				return nil, ErrTagInvalidChars
+			}
+		}
+
+		seen[tag] = true
+		normalised = append(normalised, tag)
+	}
+
+	if len(normalised) > MaxTags {
+		return nil, ErrTooManyTags
+	}
+
+	sort.Strings(normalised)
+
+	return normalised, nil
+}
+
+// ValidateNote trims a note and checks its length. An empty note clears
+// it.
+func ValidateNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxNoteLength {
+		return "", ErrNoteTooLong
+	}
+
+	return note, nil
+}
+
+func SetFavorite(userPokemon *UserPokemon, favorite bool) {
+	userPokemon.Favorite = favorite
+}
+
+// SetTags replaces the Pokémon's tags. They must already have passed
+// NormaliseTags.
+func SetTags(userPokemon *UserPokemon, tags []string) {
+	userPokemon.Tags = tags
+}
+
+// SetNote replaces the Pokémon's note. It must already have passed
+// ValidateNote.
+func SetNote(userPokemon *UserPokemon, note string) {
+	userPokemon.Note = note
+}
+
+// ClearAnnotations drops the favorite flag, tags and note, which belong to
+// the trainer who set them and must not follow the Pokémon to a new one.
+func ClearAnnotations(userPokemon *UserPokemon) {
+	userPokemon.Favorite = false
+	userPokemon.Tags = []string{}
+	userPokemon.Note = ""
+}
+
+// HasTag reports whether the Pokémon carries tag, ignoring case.
+func HasTag(userPokemon UserPokemon, tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range userPokemon.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListFilter narrows a collection listing. Nil and empty fields match
+// everything.
+type ListFilter struct {
+	Favorite *bool
+	Tag      string
+}
+
+func (f ListFilter) matches(userPokemon UserPokemon) bool {
+	if f.Favorite != nil && userPokemon.Favorite != *f.Favorite {
+		return false
+	}
+
+	if f.Tag != "" && !HasTag(userPokemon, f.Tag) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,110 @@
+package userspokemon
+
+import (
+	"math"
+	"mda/encounters"
+	"mda/inventory"
+)
+
+// Ball is the subset of inventory items that can be thrown at a Pokémon.
+type Ball = inventory.Item
+
+const (
+	PokeBall   = inventory.PokeBall
+	GreatBall  = inventory.GreatBall
+	UltraBall  = inventory.UltraBall
+	MasterBall = inventory.MasterBall
+)
+
+var ballBonus = map[Ball]float64{
+	PokeBall:   1,
+	GreatBall:  1.5,
+	UltraBall:  2,
+	MasterBall: 255,
+}
+
+// Status is the wild Pokémon's condition, rolled when the encounter spawns.
+type Status = encounters.Status
+
+var statusBonus = map[Status]float64{
+	encounters.StatusNone:      1,
+	encounters.StatusSleep:     2.5,
+	encounters.StatusFreeze:    2.5,
+	encounters.StatusParalysis: 1.5,
+	encounters.StatusPoison:    1.5,
+	encounters.StatusBurn:      1.5,
+}
+
+const shakeChecks = 4
+
+type CatchResult struct {
+	Probability float64 `json:"probability"`
+	Shakes      int     `json:"shakes"`
+	Caught      bool    `json:"caught"`
+
+	AttemptsLeft int `json:"attempts_left"`
+}
+
+func ParseBall(s string) (Ball, error) {
+	if s == "" {
+		return PokeBall, nil
+	}
+
+	ball := Ball(s)
+	if _, ok := ballBonus[ball]; !ok {
+		return "", ErrInvalidBall
+	}
+
+	return ball, nil
+}
+
+// catchValue is the modified catch rate "a" from the generation III/IV
+// formula, with the target's remaining HP given as a percentage.
+func catchValue(captureRate int, ball Ball, status Status, hpPercent int) float64 {
+	if hpPercent <= 0 || hpPercent > 100 {
+		hpPercent = 100
+	}
+
+	hp := (3*100 - 2*float64(hpPercent)) / (3 * 100)
+
+	return hp * float64(captureRate) * ballBonus[ball] * statusBonus[status]
+}
+
+// shakeThreshold is "b": each of the four shake checks passes when a random
+// number in [0, 65536) falls below it.
+func shakeThreshold(a float64) float64 {
+	return 1048560 / math.Sqrt(math.Sqrt(16711680/a))
+}
+
+// RollCatch runs the shake checks for a single throw. The returned
+// probability is the chance the throw had, not an estimate from the roll.
+func RollCatch(captureRate int, ball Ball, status Status, hpPercent int) CatchResult {
+	a := catchValue(captureRate, ball, status, hpPercent)
+
+	if ball == MasterBall || a >= 255 {
+		return CatchResult{Probability: 1, Shakes: shakeChecks - 1, Caught: true}
+	}
+
+	if a <= 0 {
+		return CatchResult{}
+	}
+
+	b := shakeThreshold(a)
+	result := CatchResult{
+		Probability: math.Pow(b/65536, shakeChecks),
+	}
+
+	for i := 0; i < shakeChecks; i++ {
+		if float64(randIntn(65536)) >= b {
+			return result
+		}
+
+		if i < shakeChecks-1 {
+			result.Shakes++
+		}
+	}
+
+	result.Caught = true
+
+	return result
+}
@@ -0,0 +1,140 @@
+package userspokemon
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"mda/encounters"
+)
+
+// useSeed fixes the source behind every roll for one test.
+func useSeed(t *testing.T, seed int64) {
+	SetRandSource(rand.NewSource(seed))
+	t.Cleanup(func() {
+		SetRandSource(rand.NewSource(time.Now().UnixNano()))
+	})
+}
+
+func TestCatchValue(t *testing.T) {
+	cases := []struct {
+		name        string
+		captureRate int
+		ball        Ball
+		status      Status
+		hpPercent   int
+		want        float64
+	}{
+		{"full hp", 45, PokeBall, encounters.StatusNone, 100, 15},
+		{"one percent hp", 45, PokeBall, encounters.StatusNone, 1, 44.7},
+		{"half hp", 45, PokeBall, encounters.StatusNone, 50, 30},
+		{"out of range hp counts as full", 45, PokeBall, encounters.StatusNone, 0, 15},
+		{"great ball", 45, GreatBall, encounters.StatusNone, 100, 22.5},
+		{"ultra ball", 45, UltraBall, encounters.StatusNone, 100, 30},
+		{"asleep", 45, PokeBall, encounters.StatusSleep, 100, 37.5},
+		{"frozen", 45, PokeBall, encounters.StatusFreeze, 100, 37.5},
+		{"paralysed", 45, PokeBall, encounters.StatusParalysis, 100, 22.5},
+		{"poisoned", 45, PokeBall, encounters.StatusPoison, 100, 22.5},
+		{"burnt", 45, PokeBall, encounters.StatusBurn, 100, 22.5},
+		{"bonuses stack", 3, UltraBall, encounters.StatusSleep, 1, 3 * 298.0 / 300 * 2 * 2.5},
+	}
+
+	for _, c := range cases {
+		got := catchValue(c.captureRate, c.ball, c.status, c.hpPercent)
+		if math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRollCatchCertainCatches(t *testing.T) {
+	useSeed(t, 1)
+
+	cases := []struct {
+		name        string
+		captureRate int
+		ball        Ball
+		status      Status
+	}{
+		{"master ball", 3, MasterBall, encounters.StatusNone},
+		{"catch value over 255", 255, UltraBall, encounters.StatusSleep},
+	}
+
+	for _, c := range cases {
+		result := RollCatch(c.captureRate, c.ball, c.status, 100)
+		if !result.Caught || result.Probability != 1 || result.Shakes != shakeChecks-1 {
+			t.Errorf("%s: got %+v", c.name, result)
+		}
+	}
+
+	if result := RollCatch(0, PokeBall, encounters.StatusNone, 100); result.Caught || result.Probability != 0 {
+		t.Errorf("capture rate 0: got %+v", result)
+	}
+}
+
+func TestRollCatchBonusesRaiseProbability(t *testing.T) {
+	useSeed(t, 1)
+
+	base := RollCatch(45, PokeBall, encounters.StatusNone, 100).Probability
+
+	cases := []struct {
+		name      string
+		ball      Ball
+		status    Status
+		hpPercent int
+	}{
+		{"great ball", GreatBall, encounters.StatusNone, 100},
+		{"ultra ball", UltraBall, encounters.StatusNone, 100},
+		{"asleep", PokeBall, encounters.StatusSleep, 100},
+		{"paralysed", PokeBall, encounters.StatusParalysis, 100},
+		{"low hp", PokeBall, encounters.StatusNone, 10},
+	}
+
+	for _, c := range cases {
+		got := RollCatch(45, c.ball, c.status, c.hpPercent).Probability
+		if got <= base {
+			t.Errorf("%s: probability %v is not above the plain throw's %v", c.name, got, base)
+		}
+	}
+}
+
+func TestRollCatchIsDeterministic(t *testing.T) {
+	throw := func() []CatchResult {
+		useSeed(t, 42)
+
+		results := make([]CatchResult, 50)
+		for i := range results {
+			results[i] = RollCatch(45, GreatBall, encounters.StatusParalysis, 60)
+		}
+
+		return results
+	}
+
+	first, second := throw(), throw()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("throw %d differs with the same seed: %+v and %+v", i, first[i], second[i])
+		}
+	}
+}
+
+func TestRollCatchMatchesProbability(t *testing.T) {
+	useSeed(t, 7)
+
+	const throws = 20000
+
+	caught := 0
+	var probability float64
+	for i := 0; i < throws; i++ {
+		result := RollCatch(45, PokeBall, encounters.StatusNone, 100)
+		probability = result.Probability
+		if result.Caught {
+			caught++
+		}
+	}
+
+	if rate := float64(caught) / throws; math.Abs(rate-probability) > 0.02 {
+		t.Fatalf("caught %.3f of throws, expected about %.3f", rate, probability)
+	}
+}
@@ -13,8 +13,63 @@ var (
 	ErrPokemonNotFound        = errors.New("pokemon not found")
 	ErrPokemonAlreadyReleased = errors.New("pokemon already released")
 	ErrPokemonNotReleased     = errors.New("pokemon not released")
+	ErrInvalidBall            = errors.New("invalid ball")
+	ErrEncounterRequired      = errors.New("encounter_id is required")
+	ErrInvalidLevel           = errors.New("level must be between 1 and 100")
+	ErrInvalidExperience      = errors.New("experience must be positive")
+	ErrInvalidOdds            = errors.New("odds cannot be negative")
+
+	ErrNicknameEmpty        = errors.New("nickname is required")
+	ErrNicknameTooLong      = errors.New("nickname must be at most 50 characters")
+	ErrNicknameInvalidChars = errors.New("nickname may only contain letters, digits, spaces and - ' . _ ♀ ♂")
+	ErrNicknameNotAllowed   = errors.New("nickname is not allowed")
+
+	ErrTooManyTags     = errors.New("a pokemon can have at most 10 tags")
+	ErrTagTooLong      = errors.New("tags must be at most 30 characters")
+	ErrTagInvalidChars = errors.New("tags may only contain letters, digits, - and _")
+	ErrNoteTooLong     = errors.New("note must be at most 500 characters")
+	ErrPokemonFavorite = errors.New("pokemon is a favorite, unfavorite it first")
+	ErrReleaseEmpty    = errors.New("ids must list at least one pokemon")
+	ErrTooManyReleases = errors.New("at most 100 pokemon can be released at once")
+
+	ErrPokemonCannotEvolve   = errors.New("pokemon does not evolve any further")
+	ErrEvolutionNotMet       = errors.New("pokemon does not meet the requirements to evolve")
+	ErrEvolutionAmbiguous    = errors.New("pokemon can evolve into several species, choose one with into")
+	ErrEvolutionNotSupported = errors.New("this evolution is not supported")
+	ErrEvolutionChanged      = errors.New("pokemon changed while evolving, try again")
+
+	ErrTradeNotFound   = errors.New("trade not found")
+	ErrTradeWithSelf   = errors.New("cannot trade with yourself")
+	ErrTradeEmpty      = errors.New("a trade needs at least one pokemon on each side")
+	ErrTradeDuplicate  = errors.New("a pokemon can only appear once in a trade")
+	ErrTradeNotOwned   = errors.New("pokemon in the trade is no longer owned by that trainer")
+	ErrTradeExpired    = errors.New("trade expired")
+	ErrTradeClosed     = errors.New("trade is no longer pending")
+	ErrTradeForbidden  = errors.New("not allowed to act on this trade")
+	ErrTradeInvalidTTL = errors.New("trade TTL must be positive")
+
+	ErrPartyFull          = errors.New("party is full")
+	ErrPartyInvalidSlot   = errors.New("party slot must be between 1 and 6")
+	ErrPartyOrderMismatch = errors.New("order must list every pokemon in the party exactly once")
+	ErrBoxNotFound        = errors.New("box not found")
+	ErrBoxFull            = errors.New("box is full")
+	ErrBoxNameRequired    = errors.New("box name is required")
+	ErrBoxInvalidCapacity = errors.New("box capacity must be positive")
+	ErrLocationRequired   = errors.New("either party_slot or box_id is required")
+
+	ErrPokedexUnavailable = errors.New("species catalogue has not been synced yet")
 )
 
+func SetDefaultBoxCapacity(capacity int) error {
+	if capacity <= 0 {
+		return ErrBoxInvalidCapacity
+	}
+
+	defaultBoxCapacity = capacity
+
+	return nil
+}
+
 func SetPool(newPool *pgxpool.Pool) error {
 	if newPool == nil {
 		return errors.New("Cannot assign nil pool")
@@ -0,0 +1,84 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"strings"
+	"time"
+)
+
+const (
+	HistoryCatch     = "catch"
+	HistoryRelease   = "release"
+	HistoryUnrelease = "unrelease"
+	HistoryRename    = "rename"
+	HistoryTrade     = "trade"
+	HistoryLevelUp   = "level_up"
+	HistoryEvolve    = "evolve"
+)
+
+// HistoryEvent is one change to an owned Pokémon. Before and After hold
+// only the fields the change touched; Before is empty for a catch.
+// PokemonId is the species after the change.
+type HistoryEvent struct {
+	Id            ulid.ULID
+	UserPokemonId ulid.ULID
+	PokemonId     int
+	Kind          string
+	OwnerId       ulid.ULID
+	ActorId       *ulid.ULID
+	Before        map[string]interface{}
+	After         map[string]interface{}
+	CreatedAt     time.Time
+}
+
+// NewHistoryEvent compares two states of a Pokémon. The actor is whoever
+// is signed in on ctx, if anyone.
+func NewHistoryEvent(ctx context.Context, kind string, before *UserPokemon, after UserPokemon) (HistoryEvent, error) {
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return HistoryEvent{}, err
+	}
+
+	event := HistoryEvent{
+		Id:            id,
+		UserPokemonId: after.Id,
+		PokemonId:     after.PokemonId,
+		Kind:          kind,
+		OwnerId:       after.UserId,
+		Before:        map[string]interface{}{},
+		After:         map[string]interface{}{},
+		CreatedAt:     time.Now(),
+	}
+
+	if actorId, err := helper.CurrentUserId(ctx); err == nil {
+		event.ActorId = &actorId
+	}
+
+	afterFields := historyFields(after)
+	if before == nil {
+		event.After = afterFields
+		return event, nil
+	}
+
+	for key, value := range historyFields(*before) {
+		if afterFields[key] != value {
+			event.Before[key] = value
+			event.After[key] = afterFields[key]
+		}
+	}
+
+	return event, nil
+}
+
+func historyFields(userPokemon UserPokemon) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":    userPokemon.UserId.String(),
+		"pokemon_id": userPokemon.PokemonId,
+		"nickname":   strings.TrimSpace(userPokemon.Nickname),
+		"released":   userPokemon.Released,
+		"level":      userPokemon.Level,
+		"shiny":      userPokemon.Shiny,
+	}
+}
@@ -0,0 +1,33 @@
+package userspokemon
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (e HistoryEvent) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id            ulid.ULID              `json:"id"`
+		UserPokemonId ulid.ULID              `json:"user_pokemon_id"`
+		PokemonId     int                    `json:"pokemon_id"`
+		Kind          string                 `json:"kind"`
+		OwnerId       ulid.ULID              `json:"owner_id"`
+		ActorId       *ulid.ULID             `json:"actor_id"`
+		Before        map[string]interface{} `json:"before"`
+		After         map[string]interface{} `json:"after"`
+		CreatedAt     time.Time              `json:"created_at"`
+	}
+
+	j.Id = e.Id
+	j.UserPokemonId = e.UserPokemonId
+	j.PokemonId = e.PokemonId
+	j.Kind = e.Kind
+	j.OwnerId = e.OwnerId
+	j.ActorId = e.ActorId
+	j.Before = e.Before
+	j.After = e.After
+	j.CreatedAt = e.CreatedAt
+
+	return json.Marshal(j)
+}
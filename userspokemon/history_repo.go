@@ -0,0 +1,41 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+func saveHistoryEvent(ctx context.Context, tx pgx.Tx, event HistoryEvent) error {
+	query := `INSERT INTO users_pokemons_events (id, user_pokemon_id, pokemon_id, kind, owner_id, actor_id, before, after, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := tx.Exec(ctx, query, event.Id, event.UserPokemonId, event.PokemonId, event.Kind, event.OwnerId, event.ActorId, event.Before, event.After, event.CreatedAt)
+
+	return err
+}
+
+func findHistoryByUserPokemonId(ctx context.Context, tx pgx.Tx, userPokemonId ulid.ULID) ([]HistoryEvent, error) {
+	query := `SELECT id, user_pokemon_id, pokemon_id, kind, owner_id, actor_id, before, after, created_at
+				FROM users_pokemons_events
+			 WHERE user_pokemon_id = $1
+			 ORDER BY id`
+
+	rows, err := tx.Query(ctx, query, userPokemonId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []HistoryEvent{}
+	for rows.Next() {
+		var e HistoryEvent
+		if err := rows.Scan(&e.Id, &e.UserPokemonId, &e.PokemonId, &e.Kind, &e.OwnerId, &e.ActorId, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,82 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"sync"
+)
+
+// EventHandler reacts to a change to an owned Pokémon inside the
+// transaction that made it. Returning an error rolls the change back.
+type EventHandler func(ctx context.Context, tx pgx.Tx, event HistoryEvent) error
+
+var (
+	handlers   []EventHandler
+	handlersMu sync.RWMutex
+)
+
+// Subscribe registers a handler for every history event. Other packages
+// use it to keep derived data, such as leaderboards, in step.
+func Subscribe(handler EventHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers = append(handlers, handler)
+}
+
+// recordHistory stores the change from before to after and hands it to the
+// subscribers. Pass a nil before for a newly caught Pokémon.
+func recordHistory(ctx context.Context, tx pgx.Tx, kind string, before *UserPokemon, after UserPokemon) error {
+	event, err := NewHistoryEvent(ctx, kind, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = saveHistoryEvent(ctx, tx, event)
+	if err != nil {
+		return err
+	}
+
+	handlersMu.RLock()
+	subscribed := append([]EventHandler(nil), handlers...)
+	handlersMu.RUnlock()
+
+	for _, handler := range subscribed {
+		if err := handler(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getHistory returns a Pokémon's history to its current owner, or to an
+// admin.
+func getHistory(ctx context.Context, userId, userPokemonId ulid.ULID, isAdmin bool) ([]HistoryEvent, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, err := findUserPokemonById(ctx, tx, userPokemonId)
+	if err != nil {
+		return nil, err
+	}
+
+	if userPokemon.UserId != userId && !isAdmin {
+		return nil, ErrPokemonNotFound
+	}
+
+	events, err := findHistoryByUserPokemonId(ctx, tx, userPokemonId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
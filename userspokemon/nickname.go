@@ -0,0 +1,81 @@
+package userspokemon
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNicknameLength matches the char(50) nickname column.
+const MaxNicknameLength = 50
+
+// NicknameFilter rejects nicknames that are not acceptable, such as
+// profanity. It returns nil for a nickname it lets through.
+type NicknameFilter func(nickname string) error
+
+var nicknameFilter NicknameFilter
+
+func SetNicknameFilter(filter NicknameFilter) {
+	nicknameFilter = filter
+}
+
+// BlocklistFilter rejects nicknames containing any of the given words,
+// ignoring case and the separators people use to slip words past a filter.
+func BlocklistFilter(words []string) NicknameFilter {
+	blocked := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = normaliseNickname(word); word != "" {
+			blocked = append(blocked, word)
+		}
+	}
+
+	return func(nickname string) error {
+		normalised := normaliseNickname(nickname)
+		for _, word := range blocked {
+			if strings.Contains(normalised, word) {
+				return ErrNicknameNotAllowed
+			}
+		}
+
+		return nil
+	}
+}
+
+func normaliseNickname(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// ValidateNickname trims a nickname and checks its length, its characters
+// and the configured filter: letters, digits, spaces and - ' . _ ♀ ♂ only.
+func ValidateNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+
+	if nickname == "" {
+		return "", ErrNicknameEmpty
+	}
+
+	if utf8.RuneCountInString(nickname) > MaxNicknameLength {
+		return "", ErrNicknameTooLong
+	}
+
+	for _, r := range nickname {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -'._♀♂", r) {
+			continue
+		}
+
+		return "", ErrNicknameInvalidChars
+	}
+
+	if nicknameFilter != nil {
+		if err := nicknameFilter(nickname); err != nil {
+			return "", err
+		}
+	}
+
+	return nickname, nil
+}
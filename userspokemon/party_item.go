@@ -0,0 +1,85 @@
+package userspokemon
+
+import (
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+	"time"
+)
+
+const PartySize = 6
+
+var defaultBoxCapacity = 30
+
+type Box struct {
+	Id        ulid.ULID
+	UserId    ulid.ULID
+	Name      string
+	Capacity  int
+	Position  int
+	CreatedAt time.Time
+
+	Pokemons []ulid.ULID
+}
+
+func NewBox(userId ulid.ULID, name string, capacity, position int) (Box, error) {
+	if name == "" {
+		return Box{}, ErrBoxNameRequired
+	}
+
+	if capacity <= 0 {
+		return Box{}, ErrBoxInvalidCapacity
+	}
+
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return Box{}, err
+	}
+
+	return Box{
+		Id:        id,
+		UserId:    userId,
+		Name:      name,
+		Capacity:  capacity,
+		Position:  position,
+		CreatedAt: time.Now(),
+		Pokemons:  []ulid.ULID{},
+	}, nil
+}
+
+func RenameBox(box *Box, name string) error {
+	if name == "" {
+		return ErrBoxNameRequired
+	}
+
+	box.Name = name
+
+	return nil
+}
+
+func PlaceInParty(userPokemon *UserPokemon, slot int) error {
+	if slot < 1 || slot > PartySize {
+		return ErrPartyInvalidSlot
+	}
+
+	userPokemon.PartySlot = null.IntFrom(int64(slot))
+	userPokemon.BoxId = nil
+
+	return nil
+}
+
+func PlaceInBox(userPokemon *UserPokemon, boxId ulid.ULID) {
+	userPokemon.PartySlot = null.Int{}
+	userPokemon.BoxId = &boxId
+}
+
+// ClearPlacement takes the Pokémon out of both the party and the PC, as when
+// it is released or changes hands.
+func ClearPlacement(userPokemon *UserPokemon) {
+	userPokemon.PartySlot = null.Int{}
+	userPokemon.BoxId = nil
+}
+
+func SwapPlacement(a, b *UserPokemon) {
+	a.PartySlot, b.PartySlot = b.PartySlot, a.PartySlot
+	a.BoxId, b.BoxId = b.BoxId, a.BoxId
+}
@@ -0,0 +1,27 @@
+package userspokemon
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (b Box) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id        ulid.ULID   `json:"id"`
+		Name      string      `json:"name"`
+		Capacity  int         `json:"capacity"`
+		Position  int         `json:"position"`
+		CreatedAt time.Time   `json:"created_at"`
+		Pokemons  []ulid.ULID `json:"pokemons"`
+	}
+
+	j.Id = b.Id
+	j.Name = b.Name
+	j.Capacity = b.Capacity
+	j.Position = b.Position
+	j.CreatedAt = b.CreatedAt
+	j.Pokemons = b.Pokemons
+
+	return json.Marshal(j)
+}
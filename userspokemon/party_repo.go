@@ -0,0 +1,166 @@
+package userspokemon
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+// lockTrainer serialises changes to one user's party and boxes.
+func lockTrainer(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, "party:"+userId.String())
+
+	return err
+}
+
+func findParty(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]UserPokemon, error) {
+	query := `SELECT ` + userPokemonColumns + `
+				  FROM users_pokemons
+			 WHERE user_id = $1 AND party_slot IS NOT NULL
+			 ORDER BY party_slot;`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUserPokemons(rows)
+}
+
+// compactParty renumbers the party slots 1..n, keeping their order. The
+// unique slot constraint is deferred so the renumbering can pass through
+// duplicate states.
+func compactParty(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	query := `UPDATE users_pokemons SET party_slot = ordered.slot
+				FROM (
+					SELECT id, row_number() OVER (ORDER BY party_slot) AS slot
+					  FROM users_pokemons
+					 WHERE user_id = $1 AND party_slot IS NOT NULL
+				) AS ordered
+			 WHERE users_pokemons.id = ordered.id`
+
+	_, err := tx.Exec(ctx, query, userId)
+
+	return err
+}
+
+func findBoxById(ctx context.Context, tx pgx.Tx, id, userId ulid.ULID) (Box, error) {
+	query := `SELECT id, user_id, name, capacity, position, created_at
+				FROM users_boxes
+			 WHERE id = $1 AND user_id = $2`
+
+	var box Box
+	err := tx.QueryRow(ctx, query, id, userId).Scan(&box.Id, &box.UserId, &box.Name, &box.Capacity, &box.Position, &box.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Box{}, ErrBoxNotFound
+		}
+		return Box{}, err
+	}
+
+	return box, nil
+}
+
+func findBoxesByUserId(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Box, error) {
+	query := `SELECT id, user_id, name, capacity, position, created_at
+				FROM users_boxes
+			 WHERE user_id = $1
+			 ORDER BY position`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	boxes := []Box{}
+	index := map[ulid.ULID]int{}
+
+	for rows.Next() {
+		var box Box
+		if err := rows.Scan(&box.Id, &box.UserId, &box.Name, &box.Capacity, &box.Position, &box.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		box.Pokemons = []ulid.ULID{}
+		index[box.Id] = len(boxes)
+		boxes = append(boxes, box)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT id, box_id FROM users_pokemons
+			 WHERE user_id = $1 AND box_id IS NOT NULL
+			 ORDER BY id`
+
+	rows, err = tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, boxId ulid.ULID
+		if err := rows.Scan(&id, &boxId); err != nil {
+			return nil, err
+		}
+
+		if i, ok := index[boxId]; ok {
+			boxes[i].Pokemons = append(boxes[i].Pokemons, id)
+		}
+	}
+
+	return boxes, rows.Err()
+}
+
+func countBoxPokemons(ctx context.Context, tx pgx.Tx, boxId ulid.ULID) (int, error) {
+	var count int
+	err := tx.QueryRow(ctx, `SELECT COUNT(id) FROM users_pokemons WHERE box_id = $1`, boxId).Scan(&count)
+
+	return count, err
+}
+
+func saveBox(ctx context.Context, tx pgx.Tx, box Box) error {
+	query := `INSERT INTO users_boxes (id, user_id, name, capacity, position, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				capacity = EXCLUDED.capacity,
+				position = EXCLUDED.position;`
+
+	_, err := tx.Exec(ctx, query, box.Id, box.UserId, box.Name, box.Capacity, box.Position, box.CreatedAt)
+
+	return err
+}
+
+// findBoxWithSpace returns the first box, by position, that still has room.
+func findBoxWithSpace(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (Box, error) {
+	query := `SELECT b.id, b.user_id, b.name, b.capacity, b.position, b.created_at
+				FROM users_boxes b
+			 WHERE b.user_id = $1
+			   AND (SELECT COUNT(p.id) FROM users_pokemons p WHERE p.box_id = b.id) < b.capacity
+			 ORDER BY b.position
+			 LIMIT 1`
+
+	var box Box
+	err := tx.QueryRow(ctx, query, userId).Scan(&box.Id, &box.UserId, &box.Name, &box.Capacity, &box.Position, &box.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Box{}, ErrBoxNotFound
+		}
+		return Box{}, err
+	}
+
+	return box, nil
+}
+
+func nextBoxPosition(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (int, error) {
+	var position int
+	err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(position), 0) + 1 FROM users_boxes WHERE user_id = $1`, userId).Scan(&position)
+
+	return position, err
+}
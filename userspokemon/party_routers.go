@@ -0,0 +1,230 @@
+package userspokemon
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"net/http"
+)
+
+func writePartyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPokemonNotFound), errors.Is(err, ErrBoxNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrPartyFull), errors.Is(err, ErrBoxFull), errors.Is(err, ErrPokemonAlreadyReleased):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrPartyInvalidSlot), errors.Is(err, ErrPartyOrderMismatch), errors.Is(err, ErrLocationRequired),
+		errors.Is(err, ErrBoxNameRequired), errors.Is(err, ErrBoxInvalidCapacity):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func getPartyHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	party, err := getParty(ctx, userId)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, party)
+}
+
+func reorderPartyHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		Order []ulid.ULID `json:"order"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	party, err := reorderParty(ctx, userId, j.Order)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, party)
+}
+
+func swapPokemonsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		A ulid.ULID `json:"a"`
+		B ulid.ULID `json:"b"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	swapped, err := swapPokemons(ctx, userId, j.A, j.B)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, swapped)
+}
+
+func movePokemonHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		PartySlot *int       `json:"party_slot"`
+		BoxId     *ulid.ULID `json:"box_id"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	userPokemon, err := movePokemon(ctx, userId, id, location{PartySlot: j.PartySlot, BoxId: j.BoxId})
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userPokemon)
+}
+
+func listBoxesHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	boxes, err := listBoxes(ctx, userId)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, boxes)
+}
+
+func createBoxHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		Name     string `json:"name"`
+		Capacity int    `json:"capacity"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if j.Capacity < 0 {
+		writeError(w, http.StatusBadRequest, ErrBoxInvalidCapacity)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	box, err := createBox(ctx, userId, j.Name, j.Capacity)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, box)
+}
+
+func renameBoxHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		Name string `json:"name"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	box, err := renameBox(ctx, userId, id, j.Name)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, box)
+}
@@ -0,0 +1,390 @@
+package userspokemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+type location struct {
+	PartySlot *int
+	BoxId     *ulid.ULID
+}
+
+// placePokemon puts a Pokémon in the first free party slot or, when the
+// party is full, in the first box with room, opening a new box if needed.
+// The caller must hold the trainer lock.
+func placePokemon(ctx context.Context, tx pgx.Tx, userPokemon *UserPokemon) error {
+	party, err := findParty(ctx, tx, userPokemon.UserId)
+	if err != nil {
+		return err
+	}
+
+	if len(party) < PartySize {
+		return PlaceInParty(userPokemon, len(party)+1)
+	}
+
+	box, err := findBoxWithSpace(ctx, tx, userPokemon.UserId)
+	if errors.Is(err, ErrBoxNotFound) {
+		box, err = openBox(ctx, tx, userPokemon.UserId, "", defaultBoxCapacity)
+	}
+	if err != nil {
+		return err
+	}
+
+	PlaceInBox(userPokemon, box.Id)
+
+	return nil
+}
+
+func openBox(ctx context.Context, tx pgx.Tx, userId ulid.ULID, name string, capacity int) (Box, error) {
+	position, err := nextBoxPosition(ctx, tx, userId)
+	if err != nil {
+		return Box{}, err
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("Box %d", position)
+	}
+
+	box, err := NewBox(userId, name, capacity, position)
+	if err != nil {
+		return Box{}, err
+	}
+
+	err = saveBox(ctx, tx, box)
+	if err != nil {
+		return Box{}, err
+	}
+
+	return box, nil
+}
+
+// findOwnedPokemon loads a Pokémon the trainer still holds; anything else
+// reads as not found so other trainers' ids are not revealed.
+func findOwnedPokemon(ctx context.Context, tx pgx.Tx, id, userId ulid.ULID) (UserPokemon, error) {
+	userPokemons, err := findUserPokemonsForUpdate(ctx, tx, []ulid.ULID{id})
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	userPokemon, ok := userPokemons[id]
+	if !ok || userPokemon.UserId != userId {
+		return UserPokemon{}, ErrPokemonNotFound
+	}
+
+	if userPokemon.Released {
+		return UserPokemon{}, ErrPokemonAlreadyReleased
+	}
+
+	return userPokemon, nil
+}
+
+func saveParty(ctx context.Context, tx pgx.Tx, party []UserPokemon) error {
+	for i := range party {
+		if err := PlaceInParty(&party[i], i+1); err != nil {
+			return err
+		}
+
+		if err := saveUserPokemon(ctx, tx, party[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getParty(ctx context.Context, userId ulid.ULID) ([]UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	party, err := findParty(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return party, nil
+}
+
+// reorderParty rearranges the party into the given order, which must name
+// every current member exactly once.
+func reorderParty(ctx context.Context, userId ulid.ULID, order []ulid.ULID) ([]UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTrainer(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+
+	party, err := findParty(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order) != len(party) {
+		return nil, ErrPartyOrderMismatch
+	}
+
+	members := make(map[ulid.ULID]UserPokemon, len(party))
+	for _, userPokemon := range party {
+		members[userPokemon.Id] = userPokemon
+	}
+
+	reordered := make([]UserPokemon, 0, len(order))
+	for _, id := range order {
+		userPokemon, ok := members[id]
+		if !ok {
+			return nil, ErrPartyOrderMismatch
+		}
+
+		delete(members, id)
+		reordered = append(reordered, userPokemon)
+	}
+
+	if err := saveParty(ctx, tx, reordered); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return reordered, nil
+}
+
+// swapPokemons exchanges the places of two Pokémon, whether they are in the
+// party, in boxes, or one of each.
+func swapPokemons(ctx context.Context, userId, a, b ulid.ULID) ([]UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTrainer(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+
+	first, err := findOwnedPokemon(ctx, tx, a, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := findOwnedPokemon(ctx, tx, b, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	SwapPlacement(&first, &second)
+
+	for _, userPokemon := range []UserPokemon{first, second} {
+		if err := saveUserPokemon(ctx, tx, userPokemon); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return []UserPokemon{first, second}, nil
+}
+
+// movePokemon sends a Pokémon to a party slot or to a box. Moving into the
+// party shifts later members down; leaving it closes the gap.
+func movePokemon(ctx context.Context, userId, id ulid.ULID, to location) (UserPokemon, error) {
+	if to.PartySlot == nil && to.BoxId == nil {
+		return UserPokemon{}, ErrLocationRequired
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTrainer(ctx, tx, userId); err != nil {
+		return UserPokemon{}, err
+	}
+
+	userPokemon, err := findOwnedPokemon(ctx, tx, id, userId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if to.PartySlot != nil {
+		slot := *to.PartySlot
+		if slot < 1 || slot > PartySize {
+			return UserPokemon{}, ErrPartyInvalidSlot
+		}
+
+		party, err := findParty(ctx, tx, userId)
+		if err != nil {
+			return UserPokemon{}, err
+		}
+
+		others := make([]UserPokemon, 0, PartySize)
+		for _, member := range party {
+			if member.Id != userPokemon.Id {
+				others = append(others, member)
+			}
+		}
+
+		if len(others) >= PartySize {
+			return UserPokemon{}, ErrPartyFull
+		}
+
+		if slot > len(others)+1 {
+			slot = len(others) + 1
+		}
+
+		reordered := append(append(append([]UserPokemon{}, others[:slot-1]...), userPokemon), others[slot-1:]...)
+		if err := saveParty(ctx, tx, reordered); err != nil {
+			return UserPokemon{}, err
+		}
+
+		userPokemon = reordered[slot-1]
+	} else {
+		box, err := findBoxById(ctx, tx, *to.BoxId, userId)
+		if err != nil {
+			return UserPokemon{}, err
+		}
+
+		if userPokemon.BoxId == nil || *userPokemon.BoxId != box.Id {
+			count, err := countBoxPokemons(ctx, tx, box.Id)
+			if err != nil {
+				return UserPokemon{}, err
+			}
+
+			if count >= box.Capacity {
+				return UserPokemon{}, ErrBoxFull
+			}
+		}
+
+		PlaceInBox(&userPokemon, box.Id)
+
+		if err := saveUserPokemon(ctx, tx, userPokemon); err != nil {
+			return UserPokemon{}, err
+		}
+
+		if err := compactParty(ctx, tx, userId); err != nil {
+			return UserPokemon{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UserPokemon{}, err
+	}
+
+	return userPokemon, nil
+}
+
+func listBoxes(ctx context.Context, userId ulid.ULID) ([]Box, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	boxes, err := findBoxesByUserId(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return boxes, nil
+}
+
+func createBox(ctx context.Context, userId ulid.ULID, name string, capacity int) (Box, error) {
+	if capacity == 0 {
+		capacity = defaultBoxCapacity
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Box{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTrainer(ctx, tx, userId); err != nil {
+		return Box{}, err
+	}
+
+	box, err := openBox(ctx, tx, userId, name, capacity)
+	if err != nil {
+		return Box{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Box{}, err
+	}
+
+	return box, nil
+}
+
+func renameBox(ctx context.Context, userId, id ulid.ULID, name string) (Box, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Box{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	box, err := findBoxById(ctx, tx, id, userId)
+	if err != nil {
+		return Box{}, err
+	}
+
+	err = RenameBox(&box, name)
+	if err != nil {
+		return Box{}, err
+	}
+
+	err = saveBox(ctx, tx, box)
+	if err != nil {
+		return Box{}, err
+	}
+
+	boxes, err := findBoxesByUserId(ctx, tx, userId)
+	if err != nil {
+		return Box{}, err
+	}
+
+	for _, b := range boxes {
+		if b.Id == box.Id {
+			box.Pokemons = b.Pokemons
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Box{}, err
+	}
+
+	return box, nil
+}
+
+// FindParty returns a trainer's party in slot order inside the caller's
+// transaction.
+func FindParty(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]UserPokemon, error) {
+	return findParty(ctx, tx, userId)
+}
+
+// LockParty holds the trainer's party lock for the rest of tx, so its
+// members can be neither moved nor released meanwhile.
+func LockParty(ctx context.Context, tx pgx.Tx, userId ulid.ULID) error {
+	return lockTrainer(ctx, tx, userId)
+}
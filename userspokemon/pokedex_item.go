@@ -0,0 +1,111 @@
+package userspokemon
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PokedexEntry is one species of the catalogue as the trainer knows it.
+// A species counts as seen once it has been encountered or caught.
+type PokedexEntry struct {
+	Id            int        `json:"id"`
+	Name          string     `json:"name"`
+	Generation    string     `json:"generation"`
+	Types         []string   `json:"types"`
+	Seen          bool       `json:"seen"`
+	Caught        bool       `json:"caught"`
+	FirstCaughtAt *time.Time `json:"first_caught_at,omitempty"`
+	Owned         int        `json:"owned"`
+	Duplicates    int        `json:"duplicates"`
+}
+
+type Completion struct {
+	Name          string  `json:"name,omitempty"`
+	Total         int     `json:"total"`
+	Seen          int     `json:"seen"`
+	Caught        int     `json:"caught"`
+	SeenPercent   float64 `json:"seen_percent"`
+	CaughtPercent float64 `json:"caught_percent"`
+}
+
+type Pokedex struct {
+	Completion
+	Duplicates  int            `json:"duplicates"`
+	Generations []Completion   `json:"generations"`
+	Types       []Completion   `json:"types"`
+	Entries     []PokedexEntry `json:"entries"`
+}
+
+func (c *Completion) add(entry PokedexEntry) {
+	c.Total++
+	if entry.Seen {
+		c.Seen++
+	}
+	if entry.Caught {
+		c.Caught++
+	}
+}
+
+func (c *Completion) finish() {
+	c.SeenPercent = percent(c.Seen, c.Total)
+	c.CaughtPercent = percent(c.Caught, c.Total)
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return math.Round(float64(part)*10000/float64(total)) / 100
+}
+
+// NewPokedex totals the entries overall, per generation and per type.
+// Generations keep catalogue order, types are sorted by name.
+func NewPokedex(entries []PokedexEntry) Pokedex {
+	pokedex := Pokedex{
+		Generations: []Completion{},
+		Types:       []Completion{},
+		Entries:     entries,
+	}
+
+	generations := make(map[string]int)
+	types := make(map[string]int)
+
+	for _, entry := range entries {
+		pokedex.add(entry)
+		pokedex.Duplicates += entry.Duplicates
+
+		i, ok := generations[entry.Generation]
+		if !ok {
+			i = len(pokedex.Generations)
+			generations[entry.Generation] = i
+			pokedex.Generations = append(pokedex.Generations, Completion{Name: entry.Generation})
+		}
+		pokedex.Generations[i].add(entry)
+
+		for _, t := range entry.Types {
+			i, ok := types[t]
+			if !ok {
+				i = len(pokedex.Types)
+				types[t] = i
+				pokedex.Types = append(pokedex.Types, Completion{Name: t})
+			}
+			pokedex.Types[i].add(entry)
+		}
+	}
+
+	sort.Slice(pokedex.Types, func(i, k int) bool {
+		return pokedex.Types[i].Name < pokedex.Types[k].Name
+	})
+
+	pokedex.finish()
+	for i := range pokedex.Generations {
+		pokedex.Generations[i].finish()
+	}
+	for i := range pokedex.Types {
+		pokedex.Types[i].finish()
+	}
+
+	return pokedex
+}
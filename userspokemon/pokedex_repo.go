@@ -0,0 +1,77 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+// findPokedexEntries walks the whole species catalogue. A species is caught
+// once the user has caught or evolved into it themselves, so Pokémon they
+// since released or traded away still count and ones they received in a
+// trade do not. Pokémon from before the history was kept have no catch
+// event and count for whoever holds them. Only the ones kept count as owned.
+func findPokedexEntries(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]PokedexEntry, error) {
+	query := `SELECT s.id, s.name, s.generation, s.types,
+					 c.first_caught_at, COALESCE(o.owned, 0),
+					 c.pokemon_id IS NOT NULL,
+					 c.pokemon_id IS NOT NULL OR o.pokemon_id IS NOT NULL OR e.pokemon_id IS NOT NULL
+				FROM pokemon_species s
+				LEFT JOIN (
+					SELECT pokemon_id, min(caught_at) AS first_caught_at
+					  FROM (
+						SELECT pokemon_id, created_at AS caught_at
+						  FROM users_pokemons_events
+						 WHERE owner_id = $1 AND kind IN ($2, $3)
+						UNION ALL
+						SELECT p.pokemon_id, p.captured_at
+						  FROM users_pokemons p
+						 WHERE p.user_id = $1
+						   AND NOT EXISTS (
+							SELECT 1 FROM users_pokemons_events h
+							 WHERE h.user_pokemon_id = p.id AND h.kind = $2
+						   )
+					  ) caught
+					 GROUP BY pokemon_id
+				) c ON c.pokemon_id = s.id
+				LEFT JOIN (
+					SELECT pokemon_id, count(*) AS owned
+					  FROM users_pokemons
+					 WHERE user_id = $1 AND NOT released
+					 GROUP BY pokemon_id
+				) o ON o.pokemon_id = s.id
+				LEFT JOIN (
+					SELECT DISTINCT pokemon_id
+					  FROM encounters
+					 WHERE user_id = $1
+				) e ON e.pokemon_id = s.id
+			 ORDER BY s.id`
+
+	rows, err := tx.Query(ctx, query, userId, HistoryCatch, HistoryEvolve)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []PokedexEntry{}
+	for rows.Next() {
+		var entry PokedexEntry
+		var firstCaughtAt null.Time
+
+		err := rows.Scan(&entry.Id, &entry.Name, &entry.Generation, &entry.Types,
+			&firstCaughtAt, &entry.Owned, &entry.Caught, &entry.Seen)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.FirstCaughtAt = firstCaughtAt.Ptr()
+		if entry.Owned > 1 {
+			entry.Duplicates = entry.Owned - 1
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
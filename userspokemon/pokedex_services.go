@@ -0,0 +1,37 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/oklog/ulid/v2"
+	"mda/users"
+)
+
+// getPokedex shows ownerId's Pokédex to viewerId, subject to the owner's
+// privacy settings.
+func getPokedex(ctx context.Context, viewerId, ownerId ulid.ULID) (Pokedex, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Pokedex{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = users.CheckAccess(ctx, tx, viewerId, ownerId, users.AspectPokedex)
+	if err != nil {
+		return Pokedex{}, err
+	}
+
+	entries, err := findPokedexEntries(ctx, tx, ownerId)
+	if err != nil {
+		return Pokedex{}, err
+	}
+
+	if len(entries) == 0 {
+		return Pokedex{}, ErrPokedexUnavailable
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Pokedex{}, err
+	}
+
+	return NewPokedex(entries), nil
+}
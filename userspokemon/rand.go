@@ -0,0 +1,28 @@
+package userspokemon
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngMu sync.Mutex
+)
+
+// SetRandSource replaces the source behind every roll in this package, which
+// makes catches reproducible when given a fixed seed.
+func SetRandSource(source rand.Source) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	rng = rand.New(source)
+}
+
+func randIntn(n int) int {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	return rng.Intn(n)
+}
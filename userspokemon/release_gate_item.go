@@ -0,0 +1,23 @@
+package userspokemon
+
+import (
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+// ReleaseGate is a trainer's progress through the prime-number release
+// mechanic: releases fail until Attempts reaches Threshold.
+type ReleaseGate struct {
+	UserId    ulid.ULID
+	Attempts  int
+	Threshold int
+	UpdatedAt time.Time
+}
+
+// ReleaseResult reports how a release attempt went. Attempts counts every
+// try since the last successful release, including this one.
+type ReleaseResult struct {
+	Number   int  `json:"number"`
+	Attempts int  `json:"attempts"`
+	Released bool `json:"released"`
+}
@@ -0,0 +1,46 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+// lockReleaseGate loads the trainer's gate, creating it with a fresh
+// threshold on first use, and locks it for the rest of tx.
+func lockReleaseGate(ctx context.Context, tx pgx.Tx, userId ulid.ULID, threshold int) (ReleaseGate, error) {
+	query := `INSERT INTO users_release_gates (user_id, attempts, threshold, updated_at)
+				VALUES ($1, 0, $2, $3)
+			  ON CONFLICT (user_id) DO NOTHING`
+
+	_, err := tx.Exec(ctx, query, userId, threshold, time.Now())
+	if err != nil {
+		return ReleaseGate{}, err
+	}
+
+	query = `SELECT user_id, attempts, threshold, updated_at
+				FROM users_release_gates
+			 WHERE user_id = $1
+			 FOR UPDATE`
+
+	var gate ReleaseGate
+	err = tx.QueryRow(ctx, query, userId).Scan(&gate.UserId, &gate.Attempts, &gate.Threshold, &gate.UpdatedAt)
+	if err != nil {
+		return ReleaseGate{}, err
+	}
+
+	return gate, nil
+}
+
+func saveReleaseGate(ctx context.Context, tx pgx.Tx, gate ReleaseGate) error {
+	query := `UPDATE users_release_gates SET
+				attempts = $2,
+				threshold = $3,
+				updated_at = $4
+			 WHERE user_id = $1`
+
+	_, err := tx.Exec(ctx, query, gate.UserId, gate.Attempts, gate.Threshold, gate.UpdatedAt)
+
+	return err
+}
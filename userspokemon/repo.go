@@ -5,17 +5,61 @@ import (
 	"errors"
 	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
+	"mda/pokemon"
 )
 
+const userPokemonColumns = `id, user_id, pokemon_id, nickname, captured_at, released,
+				  party_slot, box_id, level, experience, ivs, evs, nature, friendship,
+				  shiny, gender, ability_slot, favorite, tags, note`
+
+func scanUserPokemon(row pgx.Row) (UserPokemon, error) {
+	var userPokemon UserPokemon
+	var ivs, evs []int
+	err := row.Scan(
+		&userPokemon.Id, &userPokemon.UserId, &userPokemon.PokemonId, &userPokemon.Nickname, &userPokemon.CapturedAt, &userPokemon.Released,
+		&userPokemon.PartySlot, &userPokemon.BoxId, &userPokemon.Level, &userPokemon.Experience, &ivs, &evs, &userPokemon.Nature, &userPokemon.Friendship,
+		&userPokemon.Shiny, &userPokemon.Gender, &userPokemon.AbilitySlot, &userPokemon.Favorite, &userPokemon.Tags, &userPokemon.Note,
+	)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	userPokemon.IVs, err = pokemon.StatsFromSlice(ivs)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	userPokemon.EVs, err = pokemon.StatsFromSlice(evs)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	return userPokemon, nil
+}
+
+func scanUserPokemons(rows pgx.Rows) ([]UserPokemon, error) {
+	defer rows.Close()
+
+	var userPokemons []UserPokemon
+	for rows.Next() {
+		userPokemon, err := scanUserPokemon(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		userPokemons = append(userPokemons, userPokemon)
+	}
+
+	return userPokemons, rows.Err()
+}
+
 func findUserPokemonById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (UserPokemon, error) {
-	query := `SELECT id, user_id, pokemon_id, nickname, captured_at, released
+	query := `SELECT ` + userPokemonColumns + `
 				  FROM users_pokemons
 			 WHERE id = $1;`
 
-	row := tx.QueryRow(ctx, query, id)
-
-	var userPokemon UserPokemon
-	if err := row.Scan(&userPokemon.Id, &userPokemon.UserId, &userPokemon.PokemonId, &userPokemon.Nickname, &userPokemon.CapturedAt, &userPokemon.Released); err != nil {
+	userPokemon, err := scanUserPokemon(tx.QueryRow(ctx, query, id))
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return UserPokemon{}, ErrorUserPokemonNotFound
 		}
@@ -26,7 +70,7 @@ func findUserPokemonById(ctx context.Context, tx pgx.Tx, id ulid.ULID) (UserPoke
 }
 
 func findUserPokemonByUserId(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]UserPokemon, error) {
-	query := `SELECT id, user_id, pokemon_id, nickname, captured_at, released
+	query := `SELECT ` + userPokemonColumns + `
 				  FROM users_pokemons
 			 WHERE user_id = $1;`
 
@@ -34,35 +78,103 @@ func findUserPokemonByUserId(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var userPokemons []UserPokemon
-	for rows.Next() {
-		var userPokemon UserPokemon
-		if err := rows.Scan(&userPokemon.Id, &userPokemon.UserId, &userPokemon.PokemonId, &userPokemon.Nickname, &userPokemon.CapturedAt, &userPokemon.Released); err != nil {
-			return nil, err
-		}
+	return scanUserPokemons(rows)
+}
 
-		userPokemons = append(userPokemons, userPokemon)
+// findUserPokemonsForUpdate locks the rows in id order, so two transactions
+// locking overlapping sets cannot deadlock each other.
+func findUserPokemonsForUpdate(ctx context.Context, tx pgx.Tx, ids []ulid.ULID) (map[ulid.ULID]UserPokemon, error) {
+	query := `SELECT ` + userPokemonColumns + `
+				  FROM users_pokemons
+			 WHERE id = ANY($1)
+			 ORDER BY id
+			 FOR UPDATE;`
+
+	rows, err := tx.Query(ctx, query, ulidsToBytes(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := scanUserPokemons(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	userPokemons := make(map[ulid.ULID]UserPokemon, len(list))
+	for _, userPokemon := range list {
+		userPokemons[userPokemon.Id] = userPokemon
 	}
 
 	return userPokemons, nil
 }
 
 func saveUserPokemon(ctx context.Context, tx pgx.Tx, userPokemon UserPokemon) error {
-	query := `INSERT INTO users_pokemons (id, user_id, pokemon_id, nickname, captured_at, released)
-                  VALUES ($1, $2, $3, $4, $5, $6)
+	query := `INSERT INTO users_pokemons (id, user_id, pokemon_id, nickname, captured_at, released,
+                  party_slot, box_id, level, experience, ivs, evs, nature, friendship,
+                  shiny, gender, ability_slot, favorite, tags, note)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
             ON CONFLICT (id) DO UPDATE SET
                   user_id = EXCLUDED.user_id,
                   pokemon_id = EXCLUDED.pokemon_id,
                   nickname = EXCLUDED.nickname,
                   captured_at = EXCLUDED.captured_at,
-                  released = EXCLUDED.released;`
+                  released = EXCLUDED.released,
+                  party_slot = EXCLUDED.party_slot,
+                  box_id = EXCLUDED.box_id,
+                  level = EXCLUDED.level,
+                  experience = EXCLUDED.experience,
+                  ivs = EXCLUDED.ivs,
+                  evs = EXCLUDED.evs,
+                  nature = EXCLUDED.nature,
+                  friendship = EXCLUDED.friendship,
+                  shiny = EXCLUDED.shiny,
+                  gender = EXCLUDED.gender,
+                  ability_slot = EXCLUDED.ability_slot,
+                  favorite = EXCLUDED.favorite,
+                  tags = EXCLUDED.tags,
+                  note = EXCLUDED.note;`
+
+	// A nil slice would be written as NULL.
+	tags := userPokemon.Tags
+	if tags == nil {
+		tags = []string{}
+	}
 
-	_, err := tx.Exec(ctx, query, userPokemon.Id, userPokemon.UserId, userPokemon.PokemonId, userPokemon.Nickname, userPokemon.CapturedAt, userPokemon.Released)
+	_, err := tx.Exec(ctx, query, userPokemon.Id, userPokemon.UserId, userPokemon.PokemonId, userPokemon.Nickname, userPokemon.CapturedAt, userPokemon.Released,
+		userPokemon.PartySlot, userPokemon.BoxId, userPokemon.Level, userPokemon.Experience, userPokemon.IVs[:], userPokemon.EVs[:], userPokemon.Nature, userPokemon.Friendship,
+		userPokemon.Shiny, userPokemon.Gender, userPokemon.AbilitySlot, userPokemon.Favorite, tags, userPokemon.Note)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// advanceRenameCount bumps the Pokémon's rename counter and returns the
+// value it had before, which picks the next Fibonacci suffix.
+func advanceRenameCount(ctx context.Context, tx pgx.Tx, id ulid.ULID) (int, error) {
+	query := `UPDATE users_pokemons SET rename_count = rename_count + 1
+			 WHERE id = $1
+			 RETURNING rename_count - 1`
+
+	var count int
+	err := tx.QueryRow(ctx, query, id).Scan(&count)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrorUserPokemonNotFound
+		}
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func ulidsToBytes(ids []ulid.ULID) [][]byte {
+	b := make([][]byte, len(ids))
+	for i := range ids {
+		b[i] = ids[i].Bytes()
+	}
+
+	return b
+}
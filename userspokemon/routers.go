@@ -7,8 +7,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth"
 	"github.com/oklog/ulid/v2"
+	"mda/encounters"
 	"mda/helper"
+	"mda/inventory"
+	"mda/pokemon"
+	"mda/users"
 	"net/http"
+	"strconv"
 )
 
 func Router() *chi.Mux {
@@ -17,9 +22,30 @@ func Router() *chi.Mux {
 	r.Use(helper.TokenAuth)
 	r.Get("/", listUserPokemonsHandler)
 	r.Post("/", catchPokemonHandler)
+	r.Put("/released", releasePokemonsHandler)
 	r.Put("/released/{id}", releasePokemonHandler)
 	r.Put("/unreleased/{id}", unReleasePokemonHandler)
 	r.Put("/rename/{id}", renamePokemonHandler)
+	r.Get("/party", getPartyHandler)
+	r.Put("/party", reorderPartyHandler)
+	r.Post("/party/swap", swapPokemonsHandler)
+	r.Put("/{id}/location", movePokemonHandler)
+	r.Get("/pokedex", getPokedexHandler)
+	r.Get("/boxes", listBoxesHandler)
+	r.Post("/boxes", createBoxHandler)
+	r.Put("/boxes/{id}", renameBoxHandler)
+	r.Put("/{id}/nickname", setNicknameHandler)
+	r.Put("/{id}/favorite", setFavoriteHandler)
+	r.Put("/{id}/tags", setTagsHandler)
+	r.Put("/{id}/note", setNoteHandler)
+	r.Get("/{id}/stats", getStatsHandler)
+	r.Get("/{id}/history", getHistoryHandler)
+	r.Post("/{id}/evolve", evolvePokemonHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(helper.RoleMiddleware(helper.RoleAdmin))
+		r.Post("/{id}/experience", awardExperienceHandler)
+	})
 
 	return r
 }
@@ -44,6 +70,34 @@ func writeError(w http.ResponseWriter, status int, err error) {
 	writeMessage(w, status, err.Error())
 }
 
+// ownerFromQuery picks whose Pokémon to show: ?user_id= if given, the
+// current user otherwise.
+func ownerFromQuery(req *http.Request, userId ulid.ULID) (ulid.ULID, error) {
+	s := req.URL.Query().Get("user_id")
+	if s == "" {
+		return userId, nil
+	}
+
+	return ulid.Parse(s)
+}
+
+// filterFromQuery reads ?favorite= and ?tag= for the collection listing.
+func filterFromQuery(req *http.Request) (ListFilter, error) {
+	var filter ListFilter
+
+	if s := req.URL.Query().Get("favorite"); s != "" {
+		favorite, err := strconv.ParseBool(s)
+		if err != nil {
+			return ListFilter{}, fmt.Errorf("favorite must be true or false: %w", err)
+		}
+		filter.Favorite = &favorite
+	}
+
+	filter.Tag = req.URL.Query().Get("tag")
+
+	return filter, nil
+}
+
 func listUserPokemonsHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
@@ -60,7 +114,29 @@ func listUserPokemonsHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userPokemons, err := listUserPokemons(ctx, userId)
+	ownerId, err := ownerFromQuery(req, userId)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := filterFromQuery(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userPokemons, err := listUserPokemons(ctx, userId, ownerId, filter)
+	if errors.Is(err, users.ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, users.ErrorUserBlocked) || errors.Is(err, users.ErrorNotAllowed) {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
@@ -79,7 +155,9 @@ func catchPokemonHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
 	var j struct {
-		PokemonId int `json:"pokemon_id"`
+		EncounterId ulid.ULID `json:"encounter_id"`
+		Ball        string    `json:"ball"`
+		Nickname    string    `json:"nickname"`
 	}
 
 	err := json.NewDecoder(req.Body).Decode(&j)
@@ -88,6 +166,26 @@ func catchPokemonHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	ball, err := ParseBall(j.Ball)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if j.EncounterId == (ulid.ULID{}) {
+		writeError(w, http.StatusBadRequest, ErrEncounterRequired)
+		return
+	}
+
+	nickname := ""
+	if j.Nickname != "" {
+		nickname, err = ValidateNickname(j.Nickname)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
 	_, claims, _ := jwtauth.FromContext(ctx)
 	IdUser, ok := claims["user_id"].(string)
 	if !ok || IdUser == "" {
@@ -101,18 +199,72 @@ func catchPokemonHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userPokemon, err := catchPokemon(ctx, userId, j.PokemonId, "")
+	userPokemon, result, err := catchPokemon(ctx, userId, catchAttempt{
+		EncounterId: j.EncounterId,
+		Nickname:    nickname,
+		Ball:        ball,
+	})
+
+	if errors.Is(err, encounters.ErrorEncounterNotFound) || errors.Is(err, pokemon.ErrorPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, encounters.ErrorEncounterExpired) {
+		writeError(w, http.StatusGone, err)
+		return
+	}
+
+	if errors.Is(err, encounters.ErrorEncounterClosed) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if errors.Is(err, inventory.ErrorNotEnoughItems) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no %s left in your bag: %w", ball, err))
+		return
+	}
+
+	if errors.Is(err, ErrPokemonCatchFailed) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		err = json.NewEncoder(w).Encode(struct {
+			Message      string  `json:"message"`
+			Caught       bool    `json:"caught"`
+			Probability  float64 `json:"probability"`
+			Shakes       int     `json:"shakes"`
+			AttemptsLeft int     `json:"attempts_left"`
+		}{
+			Message:      "Pokemon broke free",
+			Caught:       false,
+			Probability:  result.Probability,
+			Shakes:       result.Shakes,
+			AttemptsLeft: result.AttemptsLeft,
+		})
+		if err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	response := struct {
-		Message string      `json:"message"`
-		Data    interface{} `json:"data"`
+		Message     string      `json:"message"`
+		Caught      bool        `json:"caught"`
+		Probability float64     `json:"probability"`
+		Shakes      int         `json:"shakes"`
+		Data        interface{} `json:"data"`
 	}{
-		Message: "Pokemon caught successfully",
-		Data:    userPokemon,
+		Message:     "Pokemon caught successfully",
+		Caught:      true,
+		Probability: result.Probability,
+		Shakes:      result.Shakes,
+		Data:        userPokemon,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -135,21 +287,44 @@ func releasePokemonHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = releasePokemon(ctx, id)
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
 
-	if errors.Is(err, ErrPokemonNotFound) {
+	result, err := releasePokemon(ctx, userId, id)
+
+	if errors.Is(err, ErrPokemonNotFound) || errors.Is(err, ErrorUserPokemonNotFound) {
 		writeError(w, http.StatusNotFound, err)
 		return
 	}
 
 	if errors.Is(err, ErrPokemonAlreadyReleased) {
-		writeError(w, http.StatusBadRequest, err)
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonFavorite) {
+		writeError(w, http.StatusConflict, err)
 		return
 	}
 
 	if err != nil {
 		if primeErr, ok := err.(*helper.PrimeError); ok {
-			writeError(w, http.StatusBadRequest, fmt.Errorf("Pokemon release failed: %v", primeErr))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+
+			err = json.NewEncoder(w).Encode(struct {
+				Message string `json:"message"`
+				ReleaseResult
+			}{
+				Message:       fmt.Sprintf("Pokemon release failed: %v", primeErr),
+				ReleaseResult: result,
+			})
+			if err != nil {
+				http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			}
 			return
 		}
 		writeError(w, http.StatusInternalServerError, err)
@@ -161,8 +336,10 @@ func releasePokemonHandler(w http.ResponseWriter, req *http.Request) {
 
 	err = json.NewEncoder(w).Encode(struct {
 		Message string `json:"message"`
+		ReleaseResult
 	}{
-		Message: "Pokemon released successfully",
+		Message:       "Pokemon released successfully",
+		ReleaseResult: result,
 	})
 
 	if err != nil {
@@ -170,6 +347,71 @@ func releasePokemonHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// releasePokemonsHandler releases several Pokémon at once. Like a single
+// release it can fail the gate, in which case nothing is released.
+func releasePokemonsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		Ids []ulid.ULID `json:"ids"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	result, released, err := releasePokemons(ctx, userId, j.Ids)
+
+	if errors.Is(err, ErrReleaseEmpty) || errors.Is(err, ErrTooManyReleases) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonAlreadyReleased) || errors.Is(err, ErrPokemonFavorite) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if primeErr, ok := err.(*helper.PrimeError); ok {
+		writeJSON(w, http.StatusBadRequest, struct {
+			Message string `json:"message"`
+			ReleaseResult
+		}{
+			Message:       fmt.Sprintf("Pokemon release failed: %v", primeErr),
+			ReleaseResult: result,
+		})
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Message string        `json:"message"`
+		Data    []UserPokemon `json:"data"`
+		ReleaseResult
+	}{
+		Message:       "Pokemon released successfully",
+		Data:          released,
+		ReleaseResult: result,
+	})
+}
+
 func unReleasePokemonHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	userPokemonId := chi.URLParam(req, "id")
@@ -180,7 +422,13 @@ func unReleasePokemonHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = unReleasePokemon(ctx, id)
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = unReleasePokemon(ctx, userId, id)
 
 	if errors.Is(err, ErrPokemonNotFound) {
 		writeError(w, http.StatusNotFound, err)
@@ -221,12 +469,18 @@ func renamePokemonHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = updatePokemon(ctx, id)
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = updatePokemon(ctx, userId, id)
 	if err != nil {
 		if errors.Is(err, ErrPokemonNotFound) {
 			writeError(w, http.StatusNotFound, err)
 		} else if errors.Is(err, ErrPokemonAlreadyReleased) {
-			writeError(w, http.StatusBadRequest, err)
+			writeError(w, http.StatusConflict, err)
 		} else {
 			writeError(w, http.StatusInternalServerError, err)
 		}
@@ -246,3 +500,355 @@ func renamePokemonHandler(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+func getStatsHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	sheet, err := getStats(ctx, userId, id)
+	if errors.Is(err, ErrPokemonNotFound) || errors.Is(err, ErrorUserPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sheet)
+}
+
+func getPokedexHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	ownerId, err := ownerFromQuery(req, userId)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pokedex, err := getPokedex(ctx, userId, ownerId)
+	if errors.Is(err, ErrPokedexUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	if errors.Is(err, users.ErrorUserNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, users.ErrorUserBlocked) || errors.Is(err, users.ErrorNotAllowed) {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pokedex)
+}
+
+func getHistoryHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	events, err := getHistory(ctx, userId, id, helper.HasRole(ctx, helper.RoleAdmin))
+	if errors.Is(err, ErrPokemonNotFound) || errors.Is(err, ErrorUserPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+func awardExperienceHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		Amount int `json:"amount"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := awardExperience(ctx, id, j.Amount)
+	if errors.Is(err, ErrPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrInvalidExperience) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonAlreadyReleased) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func evolvePokemonHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		Into int    `json:"into"`
+		Item string `json:"item"`
+	}
+
+	if req.ContentLength != 0 {
+		err = json.NewDecoder(req.Body).Decode(&j)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	request := evolveRequest{Into: j.Into}
+	if j.Item != "" {
+		request.Item, err = inventory.ParseItem(j.Item)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	userPokemon, err := evolvePokemon(ctx, userId, id, request)
+	if errors.Is(err, ErrPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonCannotEvolve) || errors.Is(err, ErrEvolutionNotMet) || errors.Is(err, ErrEvolutionNotSupported) ||
+		errors.Is(err, ErrPokemonAlreadyReleased) || errors.Is(err, ErrEvolutionChanged) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if errors.Is(err, ErrEvolutionAmbiguous) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if errors.Is(err, inventory.ErrorNotEnoughItems) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no %s left in your bag: %w", request.Item, err))
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userPokemon)
+}
+
+func setNicknameHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		Nickname string `json:"nickname"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	nickname, err := ValidateNickname(j.Nickname)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	userPokemon, err := setNickname(ctx, userId, id, nickname)
+	if errors.Is(err, ErrPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonAlreadyReleased) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userPokemon)
+}
+
+// annotate runs change on the Pokémon named in the URL and writes it back.
+func annotate(w http.ResponseWriter, req *http.Request, change func(*UserPokemon)) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	userPokemon, err := annotatePokemon(ctx, userId, id, change)
+	if errors.Is(err, ErrPokemonNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if errors.Is(err, ErrPokemonAlreadyReleased) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userPokemon)
+}
+
+func setFavoriteHandler(w http.ResponseWriter, req *http.Request) {
+	var j struct {
+		Favorite bool `json:"favorite"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	annotate(w, req, func(userPokemon *UserPokemon) {
+		SetFavorite(userPokemon, j.Favorite)
+	})
+}
+
+func setTagsHandler(w http.ResponseWriter, req *http.Request) {
+	var j struct {
+		Tags []string `json:"tags"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tags, err := NormaliseTags(j.Tags)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	annotate(w, req, func(userPokemon *UserPokemon) {
+		SetTags(userPokemon, tags)
+	})
+}
+
+func setNoteHandler(w http.ResponseWriter, req *http.Request) {
+	var j struct {
+		Note string `json:"note"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	note, err := ValidateNote(j.Note)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	annotate(w, req, func(userPokemon *UserPokemon) {
+		SetNote(userPokemon, note)
+	})
+}
@@ -2,49 +2,160 @@ package userspokemon
 
 import (
 	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
 	"github.com/oklog/ulid/v2"
 	"github.com/rs/zerolog/log"
-	"math/rand"
+	"mda/audit"
+	"mda/encounters"
 	"mda/helper"
+	"mda/inventory"
+	"mda/pokemon"
+	"mda/realtime"
+	"mda/users"
+	"strings"
 	"time"
 )
 
-func catchPokemon(ctx context.Context, userId ulid.ULID, pokemonId int, nickname string) (UserPokemon, error) {
-	rand.Seed(time.Now().UnixNano())
-	if rand.Float32() > 0.5 {
-		return UserPokemon{}, ErrPokemonCatchFailed
+type catchAttempt struct {
+	EncounterId ulid.ULID
+	Nickname    string
+	Ball        Ball
+}
+
+// catchPokemon throws a ball at the Pokémon of a live encounter. Only what the
+// encounter rolled can be caught, and only as many times as it allows.
+func catchPokemon(ctx context.Context, userId ulid.ULID, attempt catchAttempt) (UserPokemon, CatchResult, error) {
+	// PokeAPI is asked before the transaction opens, so a slow or failing
+	// lookup neither holds the encounter lock nor costs a ball. An
+	// encounter never changes its Pokémon, so the unlocked read is enough.
+	encounter, err := encounters.Find(ctx, attempt.EncounterId, userId)
+	if err != nil {
+		return UserPokemon{}, CatchResult{}, err
+	}
+
+	species, err := pokemon.FindSpecies(encounter.PokemonId)
+	if err != nil {
+		return UserPokemon{}, CatchResult{}, err
+	}
+
+	profile, err := pokemon.FindProfile(encounter.PokemonId)
+	if err != nil {
+		return UserPokemon{}, CatchResult{}, err
 	}
 
 	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return UserPokemon{}, err
+		return UserPokemon{}, CatchResult{}, err
 	}
 
-	userPokemon, err := NewPokemon(userId, pokemonId, nickname)
+	encounter, err = encounters.FindForUpdate(ctx, tx, attempt.EncounterId, userId)
 	if err != nil {
-		return UserPokemon{}, err
+		tx.Rollback(ctx)
+		return UserPokemon{}, CatchResult{}, err
+	}
+
+	err = encounters.UseAttempt(&encounter)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, CatchResult{}, err
+	}
+
+	err = inventory.Consume(ctx, tx, userId, inventory.Stack{Item: attempt.Ball, Quantity: 1})
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, CatchResult{}, err
+	}
+
+	// The ball and the attempt are spent whether or not the Pokémon stays in.
+	result := RollCatch(species.CaptureRate, attempt.Ball, encounter.Status, encounter.HpPercent)
+	result.AttemptsLeft = encounter.AttemptsLeft
+	if !result.Caught {
+		if encounter.AttemptsLeft == 0 {
+			encounters.CloseEncounter(&encounter, encounters.OutcomeFled)
+		}
+
+		err = encounters.Save(ctx, tx, encounter)
+		if err != nil {
+			tx.Rollback(ctx)
+			return UserPokemon{}, CatchResult{}, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return UserPokemon{}, CatchResult{}, err
+		}
+		return UserPokemon{}, result, ErrPokemonCatchFailed
+	}
+
+	encounters.CloseEncounter(&encounter, encounters.OutcomeCaught)
+
+	err = encounters.Save(ctx, tx, encounter)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, result, err
+	}
+
+	userPokemon, err := NewPokemon(userId, encounter.PokemonId, attempt.Nickname, species, profile)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, result, err
+	}
+
+	err = SetLevel(&userPokemon, species.GrowthRate, encounter.Level)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, result, err
+	}
+
+	userPokemon.Friendship = species.BaseHappiness
+
+	err = lockTrainer(ctx, tx, userId)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, result, err
+	}
+
+	err = placePokemon(ctx, tx, &userPokemon)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, result, err
 	}
 
 	err = saveUserPokemon(ctx, tx, userPokemon)
 	if err != nil {
 		tx.Rollback(ctx)
-		return UserPokemon{}, err
+		return UserPokemon{}, result, err
+	}
+
+	err = recordHistory(ctx, tx, HistoryCatch, nil, userPokemon)
+	if err != nil {
+		tx.Rollback(ctx)
+		return UserPokemon{}, result, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return UserPokemon{}, err
+		return UserPokemon{}, result, err
 	}
 
-	return userPokemon, nil
+	return userPokemon, result, nil
 }
 
-func listUserPokemons(ctx context.Context, userId ulid.ULID) ([]UserPokemon, error) {
+// listUserPokemons shows ownerId's collection to viewerId, subject to the
+// owner's privacy settings and narrowed by filter. Others never see
+// released Pokémon or the owner's notes.
+func listUserPokemons(ctx context.Context, viewerId, ownerId ulid.ULID, filter ListFilter) ([]UserPokemon, error) {
 	tx, err := pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userPokemons, err := findUserPokemonByUserId(ctx, tx, userId)
+	err = users.CheckAccess(ctx, tx, viewerId, ownerId, users.AspectCollection)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	userPokemons, err := findUserPokemonByUserId(ctx, tx, ownerId)
 	if err != nil {
 		tx.Rollback(ctx)
 		return nil, err
@@ -54,92 +165,290 @@ func listUserPokemons(ctx context.Context, userId ulid.ULID) ([]UserPokemon, err
 		return nil, err
 	}
 
-	return userPokemons, nil
+	visible := []UserPokemon{}
+	for _, userPokemon := range userPokemons {
+		if !filter.matches(userPokemon) {
+			continue
+		}
+
+		if viewerId != ownerId {
+			if userPokemon.Released {
+				continue
+			}
+			userPokemon.Note = ""
+		}
+
+		visible = append(visible, userPokemon)
+	}
+
+	return visible, nil
+}
+
+// passReleaseGate spends one attempt at the trainer's release gate. A
+// *helper.PrimeError means the attempt failed; the gate has moved on and
+// the caller should still commit.
+func passReleaseGate(ctx context.Context, tx pgx.Tx, userId ulid.ULID) (ReleaseResult, error) {
+	gate, err := lockReleaseGate(ctx, tx, userId, helper.GenerateThreshold())
+	if err != nil {
+		return ReleaseResult{}, err
+	}
+
+	primeGen := helper.NewPrimeGenerator(gate.Attempts, gate.Threshold)
+	primeNumber, primeErr := primeGen.GetUniquePrime()
+
+	result := ReleaseResult{Number: primeNumber, Attempts: gate.Attempts + 1}
+
+	gate.Attempts = primeGen.Attempts()
+	gate.Threshold = primeGen.Threshold()
+	gate.UpdatedAt = time.Now()
+
+	err = saveReleaseGate(ctx, tx, gate)
+	if err != nil {
+		return ReleaseResult{}, err
+	}
+
+	if primeErr != nil {
+		return result, primeErr
+	}
+
+	if err := helper.IsPrime(primeNumber); err != nil {
+		return ReleaseResult{}, err
+	}
+
+	return result, nil
 }
 
-func releasePokemon(ctx context.Context, userPokemonId ulid.ULID) error {
+// letGo releases one Pokémon that made it through the gate. The caller
+// holds the trainer lock and compacts the party afterwards.
+func letGo(ctx context.Context, tx pgx.Tx, userPokemon UserPokemon, result ReleaseResult) (UserPokemon, error) {
+	before := userPokemon
+
+	err := ReleasePokemon(&userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	ClearPlacement(&userPokemon)
+
+	err = saveUserPokemon(ctx, tx, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = recordHistory(ctx, tx, HistoryRelease, &before, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionPokemonRelease, userPokemon, map[string]interface{}{
+		"owner_id":   userPokemon.UserId.String(),
+		"pokemon_id": userPokemon.PokemonId,
+		"attempts":   result.Attempts,
+	})
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	return userPokemon, nil
+}
+
+// releasePokemon lets one of the user's Pokémon go if the trainer's
+// release gate allows it. A failed attempt is still committed, so the
+// trainer's count moves on even though nothing is released. Favorites are
+// refused before the gate is tried.
+func releasePokemon(ctx context.Context, userId, userPokemonId ulid.ULID) (ReleaseResult, error) {
 	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return err
+		return ReleaseResult{}, err
 	}
+	defer tx.Rollback(ctx)
 
-	userPokemon, err := findUserPokemonById(ctx, tx, userPokemonId)
+	err = lockTrainer(ctx, tx, userId)
 	if err != nil {
-		tx.Rollback(ctx)
-		return err
+		return ReleaseResult{}, err
 	}
 
-	if userPokemon.Released {
-		tx.Rollback(ctx)
-		return ErrPokemonAlreadyReleased
+	userPokemon, err := findOwnedPokemon(ctx, tx, userPokemonId, userId)
+	if err != nil {
+		return ReleaseResult{}, err
 	}
 
-	primeGen := helper.NewPrimeGenerator()
-	primeNumber, primeErr := primeGen.GetUniquePrime()
+	if userPokemon.Favorite {
+		return ReleaseResult{}, ErrPokemonFavorite
+	}
 
-	if primeErr != nil {
-		tx.Rollback(ctx)
-		return primeErr
+	result, err := passReleaseGate(ctx, tx, userId)
+	if _, ok := err.(*helper.PrimeError); ok {
+		if err := tx.Commit(ctx); err != nil {
+			return ReleaseResult{}, err
+		}
+		return result, err
 	}
 
-	if err := helper.IsPrime(primeNumber); err != nil {
-		tx.Rollback(ctx)
-		return err
+	if err != nil {
+		return ReleaseResult{}, err
 	}
 
-	err = ReleasePokemon(&userPokemon)
+	_, err = letGo(ctx, tx, userPokemon, result)
 	if err != nil {
-		tx.Rollback(ctx)
-		return err
+		return ReleaseResult{}, err
 	}
 
-	err = saveUserPokemon(ctx, tx, userPokemon)
+	err = compactParty(ctx, tx, userId)
 	if err != nil {
-		tx.Rollback(ctx)
-		return err
+		return ReleaseResult{}, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return err
+		return ReleaseResult{}, err
 	}
 
-	helper.DefaultAttempts++
-	if helper.DefaultAttempts == helper.DefaultThreshold {
-		helper.DefaultThreshold += 6
+	result.Released = true
+
+	return result, nil
+}
+
+// releasePokemons lets several of the user's Pokémon go on a single pass
+// of the release gate: all of them or none. Favorites are refused before
+// the gate is tried, so they have to be unfavorited first.
+func releasePokemons(ctx context.Context, userId ulid.ULID, ids []ulid.ULID) (ReleaseResult, []UserPokemon, error) {
+	if len(ids) == 0 {
+		return ReleaseResult{}, nil, ErrReleaseEmpty
 	}
 
-	return nil
+	if len(ids) > maxBulkReleases {
+		return ReleaseResult{}, nil, ErrTooManyReleases
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return ReleaseResult{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockTrainer(ctx, tx, userId)
+	if err != nil {
+		return ReleaseResult{}, nil, err
+	}
+
+	userPokemons, err := findUserPokemonsForUpdate(ctx, tx, ids)
+	if err != nil {
+		return ReleaseResult{}, nil, err
+	}
+
+	for _, id := range ids {
+		userPokemon, ok := userPokemons[id]
+		if !ok || userPokemon.UserId != userId {
+			return ReleaseResult{}, nil, ErrPokemonNotFound
+		}
+
+		if userPokemon.Released {
+			return ReleaseResult{}, nil, ErrPokemonAlreadyReleased
+		}
+
+		if userPokemon.Favorite {
+			return ReleaseResult{}, nil, ErrPokemonFavorite
+		}
+	}
+
+	result, err := passReleaseGate(ctx, tx, userId)
+	if _, ok := err.(*helper.PrimeError); ok {
+		if err := tx.Commit(ctx); err != nil {
+			return ReleaseResult{}, nil, err
+		}
+		return result, nil, err
+	}
+
+	if err != nil {
+		return ReleaseResult{}, nil, err
+	}
+
+	released := make([]UserPokemon, 0, len(userPokemons))
+	for _, id := range ids {
+		userPokemon := userPokemons[id]
+		if userPokemon.Released {
+			continue // listed twice
+		}
+
+		userPokemon, err = letGo(ctx, tx, userPokemon, result)
+		if err != nil {
+			return ReleaseResult{}, nil, err
+		}
+
+		userPokemons[id] = userPokemon
+		released = append(released, userPokemon)
+	}
+
+	err = compactParty(ctx, tx, userId)
+	if err != nil {
+		return ReleaseResult{}, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ReleaseResult{}, nil, err
+	}
+
+	result.Released = true
+
+	return result, released, nil
 }
 
-func unReleasePokemon(ctx context.Context, userPokemonId ulid.ULID) error {
+// unReleasePokemon takes one of the user's released Pokémon back.
+func unReleasePokemon(ctx context.Context, userId, userPokemonId ulid.ULID) error {
 	tx, err := pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	userPokemon, err := findUserPokemonById(ctx, tx, userPokemonId)
+	err = lockTrainer(ctx, tx, userId)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	userPokemons, err := findUserPokemonsForUpdate(ctx, tx, []ulid.ULID{userPokemonId})
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
 	}
 
+	userPokemon, ok := userPokemons[userPokemonId]
+	if !ok || userPokemon.UserId != userId {
+		tx.Rollback(ctx)
+		return ErrPokemonNotFound
+	}
+
 	if !userPokemon.Released {
 		tx.Rollback(ctx)
 		return ErrPokemonNotReleased
 	}
 
+	before := userPokemon
+
 	err = UnReleasePokemon(&userPokemon)
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
 	}
 
+	err = placePokemon(ctx, tx, &userPokemon)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
 	err = saveUserPokemon(ctx, tx, userPokemon)
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
 	}
 
+	err = recordHistory(ctx, tx, HistoryUnrelease, &before, userPokemon)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return err
 	}
@@ -147,7 +456,9 @@ func unReleasePokemon(ctx context.Context, userPokemonId ulid.ULID) error {
 	return nil
 }
 
-func updatePokemon(ctx context.Context, userPokemonId ulid.ULID) error {
+// updatePokemon renames one of the user's Pokémon with the next step of
+// its Fibonacci sequence.
+func updatePokemon(ctx context.Context, userId, userPokemonId ulid.ULID) error {
 	tx, err := pool.Begin(ctx)
 	if err != nil {
 		return err
@@ -159,19 +470,23 @@ func updatePokemon(ctx context.Context, userPokemonId ulid.ULID) error {
 		}
 	}()
 
-	userPokemon, err := findUserPokemonById(ctx, tx, userPokemonId)
+	// The row stays locked until commit, so concurrent renames of one
+	// Pokémon each get their own step of the sequence.
+	userPokemon, err := findOwnedPokemon(ctx, tx, userPokemonId, userId)
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
 	}
 
-	if userPokemon.Released {
+	renameCount, err := advanceRenameCount(ctx, tx, userPokemonId)
+	if err != nil {
 		tx.Rollback(ctx)
-		return ErrPokemonAlreadyReleased
+		return err
 	}
 
-	fibValue := helper.GetNextFibonacciValue(userPokemon.Id.String())
-	newNickname := helper.GenerateNickName(userPokemon.Nickname, fibValue)
+	fibValue := helper.CalculateFibonacci(renameCount)
+	newNickname := helper.GenerateNickName(userPokemon.Nickname, fibValue, MaxNicknameLength)
+	before := userPokemon
 
 	err = UpdatePokemon(&userPokemon, newNickname)
 	if err != nil {
@@ -185,9 +500,439 @@ func updatePokemon(ctx context.Context, userPokemonId ulid.ULID) error {
 		return err
 	}
 
+	err = recordHistory(ctx, tx, HistoryRename, &before, userPokemon)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionPokemonRename, userPokemon, map[string]interface{}{
+		"owner_id": userPokemon.UserId.String(),
+		"from":     strings.TrimSpace(before.Nickname),
+		"to":       userPokemon.Nickname,
+		"mode":     "fibonacci",
+	})
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// setNickname gives a Pokémon a nickname of the owner's choosing. The
+// nickname must already have passed ValidateNickname.
+func setNickname(ctx context.Context, userId, userPokemonId ulid.ULID, nickname string) (UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, err := findOwnedPokemon(ctx, tx, userPokemonId, userId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	before := userPokemon
+
+	err = UpdatePokemon(&userPokemon, nickname)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = saveUserPokemon(ctx, tx, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = recordHistory(ctx, tx, HistoryRename, &before, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionPokemonRename, userPokemon, map[string]interface{}{
+		"owner_id": userPokemon.UserId.String(),
+		"from":     strings.TrimSpace(before.Nickname),
+		"to":       userPokemon.Nickname,
+		"mode":     "custom",
+	})
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UserPokemon{}, err
+	}
+
+	return userPokemon, nil
+}
+
+// annotatePokemon applies a favorite, tag or note change to one of the
+// user's Pokémon. These are the owner's own bookkeeping and leave no
+// history.
+func annotatePokemon(ctx context.Context, userId, userPokemonId ulid.ULID, change func(*UserPokemon)) (UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, err := findOwnedPokemon(ctx, tx, userPokemonId, userId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	change(&userPokemon)
+
+	err = saveUserPokemon(ctx, tx, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UserPokemon{}, err
+	}
+
+	return userPokemon, nil
+}
+
+func recordEvent(ctx context.Context, tx pgx.Tx, action string, userPokemon UserPokemon, metadata map[string]interface{}) error {
+	event, err := audit.NewEvent(ctx, action, audit.TargetUserPokemon, userPokemon.Id.String())
+	if err != nil {
+		return err
+	}
+
+	for k, v := range metadata {
+		event.Metadata[k] = v
+	}
+
+	return audit.Record(ctx, tx, event)
+}
+
+func getStats(ctx context.Context, userId, userPokemonId ulid.ULID) (StatSheet, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return StatSheet{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, err := findUserPokemonById(ctx, tx, userPokemonId)
+	if err != nil {
+		return StatSheet{}, err
+	}
+
+	if userPokemon.UserId != userId {
+		return StatSheet{}, ErrPokemonNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return StatSheet{}, err
+	}
+
+	profile, err := pokemon.FindProfile(userPokemon.PokemonId)
+	if err != nil {
+		return StatSheet{}, err
+	}
+
+	species, err := pokemon.FindSpecies(userPokemon.PokemonId)
+	if err != nil {
+		return StatSheet{}, err
+	}
+
+	return NewStatSheet(userPokemon, profile, species)
+}
+
+// AwardExperience adds experience to a Pokémon inside the caller's
+// transaction and returns how many levels it gained.
+func AwardExperience(ctx context.Context, tx pgx.Tx, userPokemonId ulid.ULID, amount int) (UserPokemon, int, error) {
+	userPokemons, err := findUserPokemonsForUpdate(ctx, tx, []ulid.ULID{userPokemonId})
+	if err != nil {
+		return UserPokemon{}, 0, err
+	}
+
+	userPokemon, ok := userPokemons[userPokemonId]
+	if !ok {
+		return UserPokemon{}, 0, ErrPokemonNotFound
+	}
+
+	species, err := pokemon.FindSpecies(userPokemon.PokemonId)
+	if err != nil {
+		return UserPokemon{}, 0, err
+	}
+
+	before := userPokemon
+
+	gained, err := GainExperience(&userPokemon, species.GrowthRate, amount)
+	if err != nil {
+		return UserPokemon{}, 0, err
+	}
+
+	err = saveUserPokemon(ctx, tx, userPokemon)
+	if err != nil {
+		return UserPokemon{}, 0, err
+	}
+
+	if gained > 0 {
+		err = recordHistory(ctx, tx, HistoryLevelUp, &before, userPokemon)
+		if err != nil {
+			return UserPokemon{}, 0, err
+		}
+	}
+
+	return userPokemon, gained, nil
+}
+
+// AwardEffort adds effort values to a Pokémon inside the caller's
+// transaction.
+func AwardEffort(ctx context.Context, tx pgx.Tx, userPokemonId ulid.ULID, effort pokemon.Stats) (UserPokemon, error) {
+	userPokemons, err := findUserPokemonsForUpdate(ctx, tx, []ulid.ULID{userPokemonId})
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	userPokemon, ok := userPokemons[userPokemonId]
+	if !ok {
+		return UserPokemon{}, ErrPokemonNotFound
+	}
+
+	AddEffortValues(&userPokemon, effort)
+
+	err = saveUserPokemon(ctx, tx, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	return userPokemon, nil
+}
+
+func awardExperience(ctx context.Context, userPokemonId ulid.ULID, amount int) (ExperienceResult, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return ExperienceResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, gained, err := AwardExperience(ctx, tx, userPokemonId, amount)
+	if err != nil {
+		return ExperienceResult{}, err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionPokemonExperience, userPokemon, map[string]interface{}{
+		"owner_id":      userPokemon.UserId.String(),
+		"amount":        amount,
+		"level":         userPokemon.Level,
+		"levels_gained": gained,
+	})
+	if err != nil {
+		return ExperienceResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ExperienceResult{}, err
+	}
+
+	return ExperienceResult{LevelsGained: gained, Data: userPokemon}, nil
+}
+
+type evolveRequest struct {
+	Into int
+	Item inventory.Item
+}
+
+// pickEvolution chooses the evolution the Pokémon qualifies for right now.
+// Evolutions by item only count when that item is offered.
+func pickEvolution(userPokemon UserPokemon, evolutions []pokemon.Evolution, request evolveRequest) (pokemon.Evolution, error) {
+	if len(evolutions) == 0 {
+		return pokemon.Evolution{}, ErrPokemonCannotEvolve
+	}
+
+	var eligible []pokemon.Evolution
+	requested := false
+
+	for _, evolution := range evolutions {
+		if request.Into != 0 && evolution.SpeciesId != request.Into {
+			continue
+		}
+		requested = true
+
+		if !evolution.Supported {
+			continue
+		}
+
+		switch evolution.Trigger {
+		case pokemon.TriggerLevelUp:
+			if evolution.Item != "" || request.Item != "" {
+				continue
+			}
+
+			if userPokemon.Level < evolution.MinLevel || userPokemon.Friendship < evolution.MinHappiness {
+				continue
+			}
+		case pokemon.TriggerUseItem:
+			if string(request.Item) != evolution.Item {
+				continue
+			}
+		}
+
+		eligible = append(eligible, evolution)
+	}
+
+	if !requested {
+		return pokemon.Evolution{}, ErrPokemonCannotEvolve
+	}
+
+	if len(eligible) == 0 {
+		for _, evolution := range evolutions {
+			if evolution.Supported && (request.Into == 0 || evolution.SpeciesId == request.Into) {
+				return pokemon.Evolution{}, ErrEvolutionNotMet
+			}
+		}
+
+		return pokemon.Evolution{}, ErrEvolutionNotSupported
+	}
+
+	for _, evolution := range eligible[1:] {
+		if evolution.SpeciesId != eligible[0].SpeciesId {
+			return pokemon.Evolution{}, ErrEvolutionAmbiguous
+		}
+	}
+
+	return eligible[0], nil
+}
+
+// peekOwnedPokemon reads one of the user's Pokémon without locking it, so
+// PokeAPI can be asked about it before the transaction that changes it.
+func peekOwnedPokemon(ctx context.Context, userId, userPokemonId ulid.ULID) (UserPokemon, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, err := findUserPokemonById(ctx, tx, userPokemonId)
+	if errors.Is(err, ErrorUserPokemonNotFound) {
+		return UserPokemon{}, ErrPokemonNotFound
+	}
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if userPokemon.UserId != userId {
+		return UserPokemon{}, ErrPokemonNotFound
+	}
+
+	if userPokemon.Released {
+		return UserPokemon{}, ErrPokemonAlreadyReleased
+	}
+
+	return userPokemon, nil
+}
+
+// evolvePokemon evolves one of the user's Pokémon. The evolution data comes
+// from PokeAPI before the Pokémon is locked; under the lock the choice is
+// made again, and if the Pokémon has changed species or now qualifies for a
+// different evolution the caller is asked to retry.
+func evolvePokemon(ctx context.Context, userId, userPokemonId ulid.ULID, request evolveRequest) (UserPokemon, error) {
+	snapshot, err := peekOwnedPokemon(ctx, userId, userPokemonId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	evolutions, err := pokemon.FindEvolutions(snapshot.PokemonId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	planned, err := pickEvolution(snapshot, evolutions, request)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	from, err := pokemon.FindSpecies(snapshot.PokemonId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	to, err := pokemon.FindSpecies(planned.SpeciesId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	userPokemon, err := findOwnedPokemon(ctx, tx, userPokemonId, userId)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if userPokemon.PokemonId != snapshot.PokemonId {
+		return UserPokemon{}, ErrEvolutionChanged
+	}
+
+	evolution, err := pickEvolution(userPokemon, evolutions, request)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if evolution.SpeciesId != planned.SpeciesId {
+		return UserPokemon{}, ErrEvolutionChanged
+	}
+
+	if evolution.Trigger == pokemon.TriggerUseItem {
+		err = inventory.Consume(ctx, tx, userId, inventory.Stack{Item: request.Item, Quantity: 1})
+		if err != nil {
+			return UserPokemon{}, err
+		}
+	}
+
+	before := userPokemon
+
+	err = Evolve(&userPokemon, from, to)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = saveUserPokemon(ctx, tx, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = recordHistory(ctx, tx, HistoryEvolve, &before, userPokemon)
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = recordEvent(ctx, tx, audit.ActionPokemonEvolve, userPokemon, map[string]interface{}{
+		"owner_id": userPokemon.UserId.String(),
+		"from":     from.Id,
+		"to":       to.Id,
+		"trigger":  evolution.Trigger,
+	})
+	if err != nil {
+		return UserPokemon{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UserPokemon{}, err
+	}
+
+	err = realtime.Publish(userId, realtime.EventPokemonEvolved, map[string]interface{}{
+		"from": from.Id,
+		"to":   to.Id,
+		"data": userPokemon,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to publish evolution event")
+	}
+
+	return userPokemon, nil
+}
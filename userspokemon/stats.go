@@ -0,0 +1,51 @@
+package userspokemon
+
+import (
+	"github.com/oklog/ulid/v2"
+	"mda/pokemon"
+)
+
+// StatSheet is a Pokémon's actual stats together with everything that went
+// into computing them.
+type StatSheet struct {
+	UserPokemonId ulid.ULID      `json:"id"`
+	PokemonId     int            `json:"pokemon_id"`
+	Level         int            `json:"level"`
+	Experience    int            `json:"experience"`
+	NextLevelAt   *int           `json:"next_level_at"`
+	Nature        pokemon.Nature `json:"nature"`
+	BaseStats     pokemon.Stats  `json:"base_stats"`
+	IVs           pokemon.Stats  `json:"ivs"`
+	EVs           pokemon.Stats  `json:"evs"`
+	Stats         pokemon.Stats  `json:"stats"`
+}
+
+type ExperienceResult struct {
+	LevelsGained int         `json:"levels_gained"`
+	Data         UserPokemon `json:"data"`
+}
+
+func NewStatSheet(userPokemon UserPokemon, profile *pokemon.Profile, species *pokemon.Species) (StatSheet, error) {
+	sheet := StatSheet{
+		UserPokemonId: userPokemon.Id,
+		PokemonId:     userPokemon.PokemonId,
+		Level:         userPokemon.Level,
+		Experience:    userPokemon.Experience,
+		Nature:        userPokemon.Nature,
+		BaseStats:     profile.BaseStats,
+		IVs:           userPokemon.IVs,
+		EVs:           userPokemon.EVs,
+		Stats:         pokemon.CalculateStats(profile.BaseStats, userPokemon.IVs, userPokemon.EVs, userPokemon.Level, userPokemon.Nature),
+	}
+
+	if userPokemon.Level < pokemon.MaxLevel {
+		next, err := pokemon.ExperienceForLevel(species.GrowthRate, userPokemon.Level+1)
+		if err != nil {
+			return StatSheet{}, err
+		}
+
+		sheet.NextLevelAt = &next
+	}
+
+	return sheet, nil
+}
@@ -0,0 +1,141 @@
+package userspokemon
+
+import (
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+	"time"
+)
+
+const (
+	TradePending   = "pending"
+	TradeAccepted  = "accepted"
+	TradeDeclined  = "declined"
+	TradeCountered = "countered"
+	TradeCancelled = "cancelled"
+	TradeExpired   = "expired"
+)
+
+// Trade is an offer from the proposer to swap Offered (their Pokémon) for
+// Requested (the recipient's Pokémon).
+type Trade struct {
+	Id          ulid.ULID
+	ProposerId  ulid.ULID
+	RecipientId ulid.ULID
+	Offered     []ulid.ULID
+	Requested   []ulid.ULID
+	Status      string
+	CounterOf   *ulid.ULID
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	ResolvedAt  null.Time
+}
+
+func NewTrade(proposerId, recipientId ulid.ULID, offered, requested []ulid.ULID, ttl time.Duration) (Trade, error) {
+	if proposerId == recipientId {
+		return Trade{}, ErrTradeWithSelf
+	}
+
+	if len(offered) == 0 || len(requested) == 0 {
+		return Trade{}, ErrTradeEmpty
+	}
+
+	seen := make(map[ulid.ULID]bool)
+	for _, id := range append(append([]ulid.ULID(nil), offered...), requested...) {
+		if seen[id] {
+			return Trade{}, ErrTradeDuplicate
+		}
+		seen[id] = true
+	}
+
+	id, err := ulid.New(ulid.Timestamp(time.Now()), ulid.DefaultEntropy())
+	if err != nil {
+		return Trade{}, err
+	}
+
+	now := time.Now()
+
+	return Trade{
+		Id:          id,
+		ProposerId:  proposerId,
+		RecipientId: recipientId,
+		Offered:     offered,
+		Requested:   requested,
+		Status:      TradePending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}, nil
+}
+
+// ChangeOwner hands a traded Pokémon to its new trainer. It leaves the old
+// trainer's party or PC and takes none of their annotations along.
+func ChangeOwner(userPokemon *UserPokemon, userId ulid.ULID) {
+	if userPokemon.UserId != userId {
+		ClearAnnotations(userPokemon)
+	}
+
+	userPokemon.UserId = userId
+	ClearPlacement(userPokemon)
+}
+
+// CurrentStatus reports a pending trade past its expiry as expired, even if
+// nobody has touched it since.
+func (t Trade) CurrentStatus(now time.Time) string {
+	if t.Status == TradePending && !now.Before(t.ExpiresAt) {
+		return TradeExpired
+	}
+
+	return t.Status
+}
+
+func (t Trade) pending(now time.Time) error {
+	switch t.CurrentStatus(now) {
+	case TradePending:
+		return nil
+	case TradeExpired:
+		return ErrTradeExpired
+	default:
+		return ErrTradeClosed
+	}
+}
+
+func ResolveTrade(t *Trade, userId ulid.ULID, status string) error {
+	if err := t.pending(time.Now()); err != nil {
+		return err
+	}
+
+	switch status {
+	case TradeAccepted, TradeDeclined, TradeCountered:
+		if userId != t.RecipientId {
+			return ErrTradeForbidden
+		}
+	case TradeCancelled:
+		if userId != t.ProposerId {
+			return ErrTradeForbidden
+		}
+	}
+
+	t.Status = status
+	t.ResolvedAt = null.TimeFrom(time.Now())
+
+	return nil
+}
+
+// CounterTrade answers t with a new offer going the other way.
+func CounterTrade(t *Trade, userId ulid.ULID, offered, requested []ulid.ULID, ttl time.Duration) (Trade, error) {
+	counter, err := NewTrade(userId, t.ProposerId, offered, requested, ttl)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if err := ResolveTrade(t, userId, TradeCountered); err != nil {
+		return Trade{}, err
+	}
+
+	counter.CounterOf = &t.Id
+
+	return counter, nil
+}
+
+func (t Trade) involves(userId ulid.ULID) bool {
+	return t.ProposerId == userId || t.RecipientId == userId
+}
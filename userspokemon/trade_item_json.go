@@ -0,0 +1,35 @@
+package userspokemon
+
+import (
+	"encoding/json"
+	"github.com/oklog/ulid/v2"
+	"time"
+)
+
+func (t Trade) MarshalJSON() ([]byte, error) {
+	var j struct {
+		Id          ulid.ULID   `json:"id"`
+		ProposerId  ulid.ULID   `json:"proposer_id"`
+		RecipientId ulid.ULID   `json:"recipient_id"`
+		Offered     []ulid.ULID `json:"offered"`
+		Requested   []ulid.ULID `json:"requested"`
+		Status      string      `json:"status"`
+		CounterOf   *ulid.ULID  `json:"counter_of,omitempty"`
+		CreatedAt   time.Time   `json:"created_at"`
+		ExpiresAt   time.Time   `json:"expires_at"`
+		ResolvedAt  *time.Time  `json:"resolved_at,omitempty"`
+	}
+
+	j.Id = t.Id
+	j.ProposerId = t.ProposerId
+	j.RecipientId = t.RecipientId
+	j.Offered = t.Offered
+	j.Requested = t.Requested
+	j.Status = t.CurrentStatus(time.Now())
+	j.CounterOf = t.CounterOf
+	j.CreatedAt = t.CreatedAt
+	j.ExpiresAt = t.ExpiresAt
+	j.ResolvedAt = t.ResolvedAt.Ptr()
+
+	return json.Marshal(j)
+}
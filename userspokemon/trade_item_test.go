@@ -0,0 +1,58 @@
+package userspokemon
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestChangeOwnerDropsAnnotations(t *testing.T) {
+	boxId := ulid.Make()
+	userPokemon := UserPokemon{
+		Id:        ulid.Make(),
+		UserId:    ulid.Make(),
+		PokemonId: 25,
+		Nickname:  "sparky",
+		Favorite:  true,
+		Tags:      []string{"keeper", "shiny-hunt"},
+		Note:      "caught on my birthday, never trade",
+		PartySlot: null.IntFrom(1),
+		BoxId:     &boxId,
+	}
+
+	newOwner := ulid.Make()
+	ChangeOwner(&userPokemon, newOwner)
+
+	if userPokemon.UserId != newOwner {
+		t.Fatalf("owner = %s, want %s", userPokemon.UserId, newOwner)
+	}
+
+	if userPokemon.Favorite || len(userPokemon.Tags) != 0 || userPokemon.Note != "" {
+		t.Fatalf("annotations followed the trade: favorite %v, tags %v, note %q",
+			userPokemon.Favorite, userPokemon.Tags, userPokemon.Note)
+	}
+
+	if userPokemon.Tags == nil {
+		t.Error("tags should be empty, not nil")
+	}
+
+	if userPokemon.PartySlot.Valid || userPokemon.BoxId != nil {
+		t.Error("the Pokémon kept its old placement")
+	}
+
+	if userPokemon.Nickname != "sparky" {
+		t.Errorf("nickname = %q, the trade should keep it", userPokemon.Nickname)
+	}
+}
+
+func TestChangeOwnerToSameTrainerKeepsAnnotations(t *testing.T) {
+	owner := ulid.Make()
+	userPokemon := UserPokemon{UserId: owner, Favorite: true, Tags: []string{"keeper"}, Note: "mine"}
+
+	ChangeOwner(&userPokemon, owner)
+
+	if !userPokemon.Favorite || len(userPokemon.Tags) != 1 || userPokemon.Note != "mine" {
+		t.Fatalf("annotations were cleared without a change of owner: %+v", userPokemon)
+	}
+}
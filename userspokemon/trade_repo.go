@@ -0,0 +1,130 @@
+package userspokemon
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+)
+
+func findTradeById(ctx context.Context, tx pgx.Tx, id ulid.ULID, forUpdate bool) (Trade, error) {
+	query := `SELECT id, proposer_id, recipient_id, status, counter_of, created_at, expires_at, resolved_at
+				FROM trades
+			 WHERE id = $1`
+
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var trade Trade
+	row := tx.QueryRow(ctx, query, id)
+	if err := row.Scan(&trade.Id, &trade.ProposerId, &trade.RecipientId, &trade.Status, &trade.CounterOf, &trade.CreatedAt, &trade.ExpiresAt, &trade.ResolvedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Trade{}, ErrTradeNotFound
+		}
+		return Trade{}, err
+	}
+
+	if err := loadTradePokemons(ctx, tx, &trade); err != nil {
+		return Trade{}, err
+	}
+
+	return trade, nil
+}
+
+func loadTradePokemons(ctx context.Context, tx pgx.Tx, trade *Trade) error {
+	query := `SELECT user_pokemon_id, owner_id FROM trades_pokemons
+			 WHERE trade_id = $1
+			 ORDER BY user_pokemon_id`
+
+	rows, err := tx.Query(ctx, query, trade.Id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	trade.Offered = []ulid.ULID{}
+	trade.Requested = []ulid.ULID{}
+
+	for rows.Next() {
+		var userPokemonId, ownerId ulid.ULID
+		if err := rows.Scan(&userPokemonId, &ownerId); err != nil {
+			return err
+		}
+
+		if ownerId == trade.ProposerId {
+			trade.Offered = append(trade.Offered, userPokemonId)
+		} else {
+			trade.Requested = append(trade.Requested, userPokemonId)
+		}
+	}
+
+	return rows.Err()
+}
+
+func findTradesByUserId(ctx context.Context, tx pgx.Tx, userId ulid.ULID) ([]Trade, error) {
+	query := `SELECT id, proposer_id, recipient_id, status, counter_of, created_at, expires_at, resolved_at
+				FROM trades
+			 WHERE proposer_id = $1 OR recipient_id = $1
+			 ORDER BY id DESC`
+
+	rows, err := tx.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	trades := []Trade{}
+	for rows.Next() {
+		var trade Trade
+		if err := rows.Scan(&trade.Id, &trade.ProposerId, &trade.RecipientId, &trade.Status, &trade.CounterOf, &trade.CreatedAt, &trade.ExpiresAt, &trade.ResolvedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		trades = append(trades, trade)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range trades {
+		if err := loadTradePokemons(ctx, tx, &trades[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return trades, nil
+}
+
+func saveTrade(ctx context.Context, tx pgx.Tx, trade Trade) error {
+	query := `INSERT INTO trades (id, proposer_id, recipient_id, status, counter_of, created_at, expires_at, resolved_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  ON CONFLICT (id) DO UPDATE SET
+				status = EXCLUDED.status,
+				resolved_at = EXCLUDED.resolved_at;`
+
+	_, err := tx.Exec(ctx, query, trade.Id, trade.ProposerId, trade.RecipientId, trade.Status, trade.CounterOf, trade.CreatedAt, trade.ExpiresAt, trade.ResolvedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO trades_pokemons (trade_id, user_pokemon_id, owner_id)
+				VALUES ($1, $2, $3)
+			  ON CONFLICT (trade_id, user_pokemon_id) DO NOTHING;`
+
+	for _, id := range trade.Offered {
+		if _, err := tx.Exec(ctx, query, trade.Id, id, trade.ProposerId); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range trade.Requested {
+		if _, err := tx.Exec(ctx, query, trade.Id, id, trade.RecipientId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package userspokemon
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/oklog/ulid/v2"
+	"mda/helper"
+	"mda/users"
+	"net/http"
+)
+
+func TradeRouter() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(helper.TokenAuth)
+	r.Get("/", listTradesHandler)
+	r.Post("/", proposeTradeHandler)
+	r.Get("/{id}", getTradeHandler)
+	r.Post("/{id}/accept", resolveTradeHandler(TradeAccepted))
+	r.Post("/{id}/decline", resolveTradeHandler(TradeDeclined))
+	r.Post("/{id}/cancel", resolveTradeHandler(TradeCancelled))
+	r.Post("/{id}/counter", counterTradeHandler)
+
+	return r
+}
+
+func writeTradeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrTradeNotFound), errors.Is(err, users.ErrorUserNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrTradeForbidden), errors.Is(err, users.ErrorUserBlocked), errors.Is(err, users.ErrorNotAllowed):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, ErrTradeExpired):
+		writeError(w, http.StatusGone, err)
+	case errors.Is(err, ErrTradeClosed), errors.Is(err, ErrTradeNotOwned), errors.Is(err, ErrPokemonAlreadyReleased),
+		errors.Is(err, ErrPokemonFavorite):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrTradeWithSelf), errors.Is(err, ErrTradeEmpty), errors.Is(err, ErrTradeDuplicate):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeTrade(w http.ResponseWriter, status int, trade interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(trade)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func listTradesHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	trades, err := listTrades(ctx, userId)
+	if err != nil {
+		writeTradeError(w, err)
+		return
+	}
+
+	writeTrade(w, http.StatusOK, trades)
+}
+
+func proposeTradeHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	var j struct {
+		RecipientId ulid.ULID   `json:"recipient_id"`
+		Offered     []ulid.ULID `json:"offered"`
+		Requested   []ulid.ULID `json:"requested"`
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	trade, err := proposeTrade(ctx, userId, j.RecipientId, j.Offered, j.Requested)
+	if err != nil {
+		writeTradeError(w, err)
+		return
+	}
+
+	writeTrade(w, http.StatusCreated, trade)
+}
+
+func getTradeHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	trade, err := getTrade(ctx, id, userId)
+	if err != nil {
+		writeTradeError(w, err)
+		return
+	}
+
+	writeTrade(w, http.StatusOK, trade)
+}
+
+func resolveTradeHandler(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+
+		id, err := ulid.Parse(chi.URLParam(req, "id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		userId, err := helper.CurrentUserId(ctx)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		var trade Trade
+		if status == TradeAccepted {
+			trade, err = acceptTrade(ctx, id, userId)
+		} else {
+			trade, err = resolveTrade(ctx, id, userId, status)
+		}
+
+		if err != nil {
+			writeTradeError(w, err)
+			return
+		}
+
+		writeTrade(w, http.StatusOK, trade)
+	}
+}
+
+func counterTradeHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	id, err := ulid.Parse(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var j struct {
+		Offered   []ulid.ULID `json:"offered"`
+		Requested []ulid.ULID `json:"requested"`
+	}
+
+	err = json.NewDecoder(req.Body).Decode(&j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userId, err := helper.CurrentUserId(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	trade, err := counterTrade(ctx, id, userId, j.Offered, j.Requested)
+	if err != nil {
+		writeTradeError(w, err)
+		return
+	}
+
+	writeTrade(w, http.StatusCreated, trade)
+}
@@ -0,0 +1,353 @@
+package userspokemon
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"mda/realtime"
+	"mda/users"
+	"time"
+)
+
+var tradeTTL = 72 * time.Hour
+
+func SetTradeTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrTradeInvalidTTL
+	}
+
+	tradeTTL = ttl
+
+	return nil
+}
+
+// checkBlocked stops trades between users when either has blocked the
+// other, even ones offered before the block.
+func checkBlocked(ctx context.Context, tx pgx.Tx, trade Trade) error {
+	blocked, err := users.IsBlocked(ctx, tx, trade.ProposerId, trade.RecipientId)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return users.ErrorUserBlocked
+	}
+
+	return nil
+}
+
+// checkTradable verifies, under row locks, that each side still owns what
+// the trade lists and that none of it has been released or favorited.
+func checkTradable(ctx context.Context, tx pgx.Tx, trade Trade) (map[ulid.ULID]UserPokemon, error) {
+	ids := append(append([]ulid.ULID(nil), trade.Offered...), trade.Requested...)
+
+	userPokemons, err := findUserPokemonsForUpdate(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := map[ulid.ULID][]ulid.ULID{
+		trade.ProposerId:  trade.Offered,
+		trade.RecipientId: trade.Requested,
+	}
+
+	for ownerId, ids := range owners {
+		for _, id := range ids {
+			userPokemon, ok := userPokemons[id]
+			if !ok || userPokemon.UserId != ownerId {
+				return nil, ErrTradeNotOwned
+			}
+
+			if userPokemon.Released {
+				return nil, ErrPokemonAlreadyReleased
+			}
+
+			if userPokemon.Favorite {
+				return nil, ErrPokemonFavorite
+			}
+		}
+	}
+
+	return userPokemons, nil
+}
+
+func proposeTrade(ctx context.Context, proposerId, recipientId ulid.ULID, offered, requested []ulid.ULID) (Trade, error) {
+	trade, err := NewTrade(proposerId, recipientId, offered, requested, tradeTTL)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = users.CheckAccess(ctx, tx, proposerId, recipientId, users.AspectTrades)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	_, err = checkTradable(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	err = saveTrade(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Trade{}, err
+	}
+
+	notifyTrade(realtime.EventTradeOffered, trade, proposerId)
+
+	return trade, nil
+}
+
+func listTrades(ctx context.Context, userId ulid.ULID) ([]Trade, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	trades, err := findTradesByUserId(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+func getTrade(ctx context.Context, id, userId ulid.ULID) (Trade, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	trade, err := findTradeById(ctx, tx, id, false)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if !trade.involves(userId) {
+		return Trade{}, ErrTradeNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Trade{}, err
+	}
+
+	return trade, nil
+}
+
+// acceptTrade swaps ownership of every Pokémon in the trade in a single
+// transaction: either all of them change hands or none do.
+func acceptTrade(ctx context.Context, id, userId ulid.ULID) (Trade, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	trade, err := findTradeById(ctx, tx, id, true)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if !trade.involves(userId) {
+		return Trade{}, ErrTradeNotFound
+	}
+
+	err = ResolveTrade(&trade, userId, TradeAccepted)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	err = checkBlocked(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	// Lock both trainers in a fixed order so two crossing trades cannot
+	// deadlock on each other's party.
+	trainers := []ulid.ULID{trade.ProposerId, trade.RecipientId}
+	if trainers[1].Compare(trainers[0]) < 0 {
+		trainers[0], trainers[1] = trainers[1], trainers[0]
+	}
+
+	for _, trainerId := range trainers {
+		if err := lockTrainer(ctx, tx, trainerId); err != nil {
+			return Trade{}, err
+		}
+	}
+
+	userPokemons, err := checkTradable(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	traded := make([]UserPokemon, 0, len(userPokemons))
+	for _, id := range append(append([]ulid.ULID(nil), trade.Offered...), trade.Requested...) {
+		userPokemon := userPokemons[id]
+		before := userPokemon
+
+		newOwner := trade.ProposerId
+		if userPokemon.UserId == trade.ProposerId {
+			newOwner = trade.RecipientId
+		}
+
+		ChangeOwner(&userPokemon, newOwner)
+
+		if err := saveUserPokemon(ctx, tx, userPokemon); err != nil {
+			return Trade{}, err
+		}
+
+		if err := recordHistory(ctx, tx, HistoryTrade, &before, userPokemon); err != nil {
+			return Trade{}, err
+		}
+
+		traded = append(traded, userPokemon)
+	}
+
+	for _, trainerId := range trainers {
+		if err := compactParty(ctx, tx, trainerId); err != nil {
+			return Trade{}, err
+		}
+	}
+
+	// Only once both parties have closed their gaps can the newcomers be
+	// placed with their new trainers.
+	for _, userPokemon := range traded {
+		if err := placePokemon(ctx, tx, &userPokemon); err != nil {
+			return Trade{}, err
+		}
+
+		if err := saveUserPokemon(ctx, tx, userPokemon); err != nil {
+			return Trade{}, err
+		}
+	}
+
+	err = saveTrade(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Trade{}, err
+	}
+
+	notifyTrade(realtime.EventTradeAccepted, trade, userId)
+
+	return trade, nil
+}
+
+func resolveTrade(ctx context.Context, id, userId ulid.ULID, status string) (Trade, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	trade, err := findTradeById(ctx, tx, id, true)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if !trade.involves(userId) {
+		return Trade{}, ErrTradeNotFound
+	}
+
+	err = ResolveTrade(&trade, userId, status)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	err = saveTrade(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Trade{}, err
+	}
+
+	eventType := realtime.EventTradeDeclined
+	if status == TradeCancelled {
+		eventType = realtime.EventTradeCancelled
+	}
+	notifyTrade(eventType, trade, userId)
+
+	return trade, nil
+}
+
+func counterTrade(ctx context.Context, id, userId ulid.ULID, offered, requested []ulid.ULID) (Trade, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	trade, err := findTradeById(ctx, tx, id, true)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if !trade.involves(userId) {
+		return Trade{}, ErrTradeNotFound
+	}
+
+	counter, err := CounterTrade(&trade, userId, offered, requested, tradeTTL)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	err = checkBlocked(ctx, tx, counter)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	_, err = checkTradable(ctx, tx, counter)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	err = saveTrade(ctx, tx, trade)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	err = saveTrade(ctx, tx, counter)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Trade{}, err
+	}
+
+	notifyTrade(realtime.EventTradeCountered, counter, userId)
+
+	return counter, nil
+}
+
+// notifyTrade tells the other side of a trade what the actor just did.
+func notifyTrade(eventType string, trade Trade, actorId ulid.ULID) {
+	recipient := trade.ProposerId
+	if actorId == trade.ProposerId {
+		recipient = trade.RecipientId
+	}
+
+	if err := realtime.Publish(recipient, eventType, trade); err != nil {
+		log.Error().Err(err).Msg("failed to publish trade event")
+	}
+}
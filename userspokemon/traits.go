@@ -0,0 +1,91 @@
+package userspokemon
+
+import (
+	"mda/pokemon"
+	"strconv"
+)
+
+const SpriteURL = "https://raw.githubusercontent.com/PokeAPI/sprites/master/sprites/pokemon/"
+
+const (
+	GenderMale       = "male"
+	GenderFemale     = "female"
+	GenderGenderless = "genderless"
+	// GenderUnknown is kept by Pokémon caught before genders were rolled.
+	GenderUnknown = "unknown"
+)
+
+const HiddenAbilitySlot = 3
+
+// The odds are one in n; zero turns the roll off.
+var (
+	shinyOdds         = 4096
+	hiddenAbilityOdds = 50
+)
+
+// SetOdds changes the one in n chance of a catch being shiny and of it
+// having its species' hidden ability. Zero turns either roll off.
+func SetOdds(shiny, hiddenAbility int) error {
+	if shiny < 0 || hiddenAbility < 0 {
+		return ErrInvalidOdds
+	}
+
+	shinyOdds = shiny
+	hiddenAbilityOdds = hiddenAbility
+
+	return nil
+}
+
+func rollShiny() bool {
+	return shinyOdds > 0 && randIntn(shinyOdds) == 0
+}
+
+// rollGender follows PokeAPI's gender rate, the chance of being female in
+// eighths, or -1 for species without a gender.
+func rollGender(genderRate int) string {
+	if genderRate < 0 {
+		return GenderGenderless
+	}
+
+	if randIntn(8) < genderRate {
+		return GenderFemale
+	}
+
+	return GenderMale
+}
+
+// rollAbilitySlot picks one of the regular abilities evenly, unless the
+// hidden ability comes up. Forms without ability data get slot 1.
+func rollAbilitySlot(abilities []pokemon.Ability) int {
+	var regular []int
+	hasHidden := false
+
+	for _, ability := range abilities {
+		if ability.Hidden {
+			hasHidden = true
+		} else {
+			regular = append(regular, ability.Slot)
+		}
+	}
+
+	if hasHidden && hiddenAbilityOdds > 0 && randIntn(hiddenAbilityOdds) == 0 {
+		return HiddenAbilitySlot
+	}
+
+	if len(regular) == 0 {
+		return 1
+	}
+
+	return regular[randIntn(len(regular))]
+}
+
+// spriteURL points at the PokeAPI front sprite, the shiny one for shiny
+// Pokémon.
+func spriteURL(pokemonId int, shiny bool) string {
+	variant := ""
+	if shiny {
+		variant = "shiny/"
+	}
+
+	return SpriteURL + variant + strconv.Itoa(pokemonId) + ".png"
+}
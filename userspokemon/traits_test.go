@@ -0,0 +1,90 @@
+package userspokemon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"mda/pokemon"
+)
+
+// useOdds fixes the seed and the odds for one test.
+func useOdds(t *testing.T, shiny, hiddenAbility int) {
+	previousShiny, previousHidden := shinyOdds, hiddenAbilityOdds
+
+	useSeed(t, 1)
+	if err := SetOdds(shiny, hiddenAbility); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		SetOdds(previousShiny, previousHidden)
+	})
+}
+
+func TestNewPokemonShiny(t *testing.T) {
+	useOdds(t, 1, 0)
+
+	species := &pokemon.Species{Id: 25, Name: "pikachu", GenderRate: 4}
+	profile := &pokemon.Profile{Id: 25, Name: "pikachu", Abilities: []pokemon.Ability{
+		{Name: "static", Slot: 1},
+		{Name: "lightning-rod", Slot: 3, Hidden: true},
+	}}
+
+	userPokemon, err := NewPokemon(ulid.Make(), 25, "", species, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !userPokemon.Shiny {
+		t.Fatal("expected a shiny with odds of one in one")
+	}
+
+	if userPokemon.Nickname != "pikachu" {
+		t.Errorf("expected the species name as nickname, got %q", userPokemon.Nickname)
+	}
+
+	if userPokemon.AbilitySlot != 1 {
+		t.Errorf("hidden ability rolled with its odds off, got slot %d", userPokemon.AbilitySlot)
+	}
+
+	body, err := json.Marshal(userPokemon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var j struct {
+		SpriteURL string `json:"sprite_url"`
+	}
+	if err := json.Unmarshal(body, &j); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := SpriteURL + "shiny/25.png"; j.SpriteURL != want {
+		t.Errorf("sprite_url = %q, want %q", j.SpriteURL, want)
+	}
+}
+
+func TestNewPokemonNotShinyWhenOff(t *testing.T) {
+	useOdds(t, 0, 0)
+
+	species := &pokemon.Species{Id: 81, Name: "magnemite", GenderRate: -1}
+	profile := &pokemon.Profile{Id: 81, Name: "magnemite"}
+
+	userPokemon, err := NewPokemon(ulid.Make(), 81, "sparky", species, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userPokemon.Shiny {
+		t.Fatal("shiny rolled with its odds off")
+	}
+
+	if userPokemon.Gender != GenderGenderless {
+		t.Errorf("gender = %q, want %q", userPokemon.Gender, GenderGenderless)
+	}
+
+	if got, want := spriteURL(81, userPokemon.Shiny), SpriteURL+"81.png"; got != want {
+		t.Errorf("spriteURL = %q, want %q", got, want)
+	}
+}
@@ -1,15 +1,21 @@
 package userspokemon
 
 import (
-	"encoding/json"
 	"github.com/oklog/ulid/v2"
-	"net/http"
-	"strconv"
+	"gopkg.in/guregu/null.v4"
+	"mda/pokemon"
+	"strings"
 	"time"
 )
 
 const PokeAPIURL = "https://pokeapi.co/api/v2/pokemon/"
 
+const (
+	defaultFriendship  = 70
+	maxFriendship      = 255
+	friendshipPerLevel = 5
+)
+
 type UserPokemon struct {
 	Id         ulid.ULID
 	UserId     ulid.ULID
@@ -17,16 +23,32 @@ type UserPokemon struct {
 	Nickname   string
 	CapturedAt time.Time
 	Released   bool
+
+	Level      int
+	Experience int
+	IVs        pokemon.Stats
+	EVs        pokemon.Stats
+	Nature     pokemon.Nature
+	Friendship int
+
+	Shiny       bool
+	Gender      string
+	AbilitySlot int
+
+	Favorite bool
+	Tags     []string
+	Note     string
+
+	PartySlot null.Int
+	BoxId     *ulid.ULID
 }
 
-func NewPokemon(userId ulid.ULID, pokemonId int, nickname string) (UserPokemon, error) {
+// NewPokemon rolls a freshly caught Pokémon. species and profile come from
+// PokeAPI and are passed in, so callers can fetch them before opening a
+// transaction. An empty nickname falls back to the species name.
+func NewPokemon(userId ulid.ULID, pokemonId int, nickname string, species *pokemon.Species, profile *pokemon.Profile) (UserPokemon, error) {
 	if nickname == "" {
-		name, err := getPokemonName(pokemonId)
-		if err != nil {
-			return UserPokemon{}, err
-		}
-
-		nickname = name
+		nickname = species.Name
 	}
 
 	id, err := ulid.New(ulid.Timestamp(time.Now()), nil)
@@ -34,6 +56,11 @@ func NewPokemon(userId ulid.ULID, pokemonId int, nickname string) (UserPokemon,
 		return UserPokemon{}, err
 	}
 
+	var ivs pokemon.Stats
+	for i := range ivs {
+		ivs[i] = randIntn(pokemon.MaxIV + 1)
+	}
+
 	return UserPokemon{
 		Id:         id,
 		UserId:     userId,
@@ -41,9 +68,118 @@ func NewPokemon(userId ulid.ULID, pokemonId int, nickname string) (UserPokemon,
 		Nickname:   nickname,
 		CapturedAt: time.Now(),
 		Released:   false,
+		Level:      1,
+		Experience: 0,
+		IVs:        ivs,
+		Nature:     pokemon.Natures[randIntn(len(pokemon.Natures))],
+		Friendship: defaultFriendship,
+
+		Shiny:       rollShiny(),
+		Gender:      rollGender(species.GenderRate),
+		AbilitySlot: rollAbilitySlot(profile.Abilities),
+
+		Tags: []string{},
 	}, nil
 }
 
+// SetLevel puts the Pokémon at the start of a level on its growth curve.
+func SetLevel(userPokemon *UserPokemon, growthRate string, level int) error {
+	if level < 1 || level > pokemon.MaxLevel {
+		return ErrInvalidLevel
+	}
+
+	experience, err := pokemon.ExperienceForLevel(growthRate, level)
+	if err != nil {
+		return err
+	}
+
+	userPokemon.Level = level
+	userPokemon.Experience = experience
+
+	return nil
+}
+
+// GainExperience adds experience and levels the Pokémon up along its growth
+// curve. Experience stops counting once the Pokémon reaches the level cap.
+func GainExperience(userPokemon *UserPokemon, growthRate string, amount int) (int, error) {
+	if userPokemon.Released {
+		return 0, ErrPokemonAlreadyReleased
+	}
+
+	if amount <= 0 {
+		return 0, ErrInvalidExperience
+	}
+
+	maxExperience, err := pokemon.ExperienceForLevel(growthRate, pokemon.MaxLevel)
+	if err != nil {
+		return 0, err
+	}
+
+	experience := userPokemon.Experience + amount
+	if experience > maxExperience {
+		experience = maxExperience
+	}
+
+	level, err := pokemon.LevelForExperience(growthRate, experience)
+	if err != nil {
+		return 0, err
+	}
+
+	gained := level - userPokemon.Level
+	userPokemon.Experience = experience
+	userPokemon.Level = level
+	AddFriendship(userPokemon, gained*friendshipPerLevel)
+
+	return gained, nil
+}
+
+// AddEffortValues raises EVs, capped per stat and in total as in the games.
+func AddEffortValues(userPokemon *UserPokemon, effort pokemon.Stats) {
+	for stat, amount := range effort {
+		room := pokemon.MaxTotalEV - userPokemon.EVs.Total()
+		if room <= 0 {
+			return
+		}
+
+		if amount > room {
+			amount = room
+		}
+
+		userPokemon.EVs[stat] += amount
+		if userPokemon.EVs[stat] > pokemon.MaxStatEV {
+			userPokemon.EVs[stat] = pokemon.MaxStatEV
+		}
+	}
+}
+
+func AddFriendship(userPokemon *UserPokemon, amount int) {
+	userPokemon.Friendship += amount
+
+	if userPokemon.Friendship > maxFriendship {
+		userPokemon.Friendship = maxFriendship
+	}
+
+	if userPokemon.Friendship < 0 {
+		userPokemon.Friendship = 0
+	}
+}
+
+// Evolve turns the Pokémon into the next species. A nickname that was just
+// the old species name follows the evolution; a chosen one is kept.
+func Evolve(userPokemon *UserPokemon, from, to *pokemon.Species) error {
+	if userPokemon.Released {
+		return ErrPokemonAlreadyReleased
+	}
+
+	if strings.EqualFold(strings.TrimSpace(userPokemon.Nickname), from.Name) {
+		userPokemon.Nickname = to.Name
+	}
+
+	userPokemon.PokemonId = to.Id
+
+	return nil
+}
+
 func ReleasePokemon(userPokemon *UserPokemon) error {
 	if userPokemon.Released {
 		return ErrPokemonAlreadyReleased
@@ -67,21 +203,3 @@ func UnReleasePokemon(userPokemon *UserPokemon) error {
 	userPokemon.Released = false
 	return nil
 }
-
-func getPokemonName(pokemonId int) (string, error) {
-	resp, err := http.Get(PokeAPIURL + strconv.Itoa(pokemonId))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Name string `json:"name"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-
-	return result.Name, nil
-}
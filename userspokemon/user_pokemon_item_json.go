@@ -3,6 +3,8 @@ package userspokemon
 import (
 	"encoding/json"
 	"github.com/oklog/ulid/v2"
+	"gopkg.in/guregu/null.v4"
+	"mda/pokemon"
 	"time"
 )
 
@@ -14,6 +16,25 @@ func (u UserPokemon) MarshalJSON() ([]byte, error) {
 		Nickname   string    `json:"nickname"`
 		CapturedAt time.Time `json:"captured_at"`
 		Released   bool      `json:"released"`
+
+		Level      int            `json:"level"`
+		Experience int            `json:"experience"`
+		IVs        pokemon.Stats  `json:"ivs"`
+		EVs        pokemon.Stats  `json:"evs"`
+		Nature     pokemon.Nature `json:"nature"`
+		Friendship int            `json:"friendship"`
+
+		Shiny       bool   `json:"shiny"`
+		Gender      string `json:"gender"`
+		AbilitySlot int    `json:"ability_slot"`
+		SpriteURL   string `json:"sprite_url"`
+
+		Favorite bool     `json:"favorite"`
+		Tags     []string `json:"tags"`
+		Note     string   `json:"note,omitempty"`
+
+		PartySlot *int64     `json:"party_slot,omitempty"`
+		BoxId     *ulid.ULID `json:"box_id,omitempty"`
 	}
 
 	j.Id = u.Id
@@ -22,6 +43,21 @@ func (u UserPokemon) MarshalJSON() ([]byte, error) {
 	j.Nickname = u.Nickname
 	j.CapturedAt = u.CapturedAt
 	j.Released = u.Released
+	j.Level = u.Level
+	j.Experience = u.Experience
+	j.IVs = u.IVs
+	j.EVs = u.EVs
+	j.Nature = u.Nature
+	j.Friendship = u.Friendship
+	j.Shiny = u.Shiny
+	j.Gender = u.Gender
+	j.AbilitySlot = u.AbilitySlot
+	j.SpriteURL = spriteURL(u.PokemonId, u.Shiny)
+	j.Favorite = u.Favorite
+	j.Tags = u.Tags
+	j.Note = u.Note
+	j.PartySlot = u.PartySlot.Ptr()
+	j.BoxId = u.BoxId
 
 	return json.Marshal(j)
 }
@@ -34,6 +70,24 @@ func (u *UserPokemon) UnmarshalJSON(data []byte) error {
 		Nickname   string    `json:"nickname"`
 		CapturedAt string    `json:"captured_at"`
 		Released   bool      `json:"released"`
+
+		Level      int            `json:"level"`
+		Experience int            `json:"experience"`
+		IVs        pokemon.Stats  `json:"ivs"`
+		EVs        pokemon.Stats  `json:"evs"`
+		Nature     pokemon.Nature `json:"nature"`
+		Friendship int            `json:"friendship"`
+
+		Shiny       bool   `json:"shiny"`
+		Gender      string `json:"gender"`
+		AbilitySlot int    `json:"ability_slot"`
+
+		Favorite bool     `json:"favorite"`
+		Tags     []string `json:"tags"`
+		Note     string   `json:"note"`
+
+		PartySlot null.Int   `json:"party_slot"`
+		BoxId     *ulid.ULID `json:"box_id"`
 	}
 
 	err := json.Unmarshal(data, &j)
@@ -52,6 +106,20 @@ func (u *UserPokemon) UnmarshalJSON(data []byte) error {
 	u.Nickname = j.Nickname
 	u.CapturedAt = CapturedAt
 	u.Released = j.Released
+	u.Level = j.Level
+	u.Experience = j.Experience
+	u.IVs = j.IVs
+	u.EVs = j.EVs
+	u.Nature = j.Nature
+	u.Friendship = j.Friendship
+	u.Shiny = j.Shiny
+	u.Gender = j.Gender
+	u.AbilitySlot = j.AbilitySlot
+	u.Favorite = j.Favorite
+	u.Tags = j.Tags
+	u.Note = j.Note
+	u.PartySlot = j.PartySlot
+	u.BoxId = j.BoxId
 
 	return nil
 }